@@ -1,13 +1,17 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/Rach17/Go-RSS-Aggregator/data"
 	"github.com/Rach17/Go-RSS-Aggregator/service"
 	"github.com/Rach17/Go-RSS-Aggregator/utils"
 	"github.com/Rach17/Go-RSS-Aggregator/db"
+	"github.com/google/uuid"
 )
 
 
@@ -24,8 +28,11 @@ func NewFeedHandler(feedService *service.FeedService, userService *service.UserS
 }
 
 func (h *FeedHandler) handleCreateFeed(w http.ResponseWriter, r *http.Request) {
+	// A feed with credentials or headers is always private to its creator
 	type parameters struct {
-		URL string `json:"url"`
+		URL         string               `json:"url"`
+		Private     bool                 `json:"private"`
+		Credentials data.FeedCredentials `json:"credentials"`
 	}
 
 	var params parameters
@@ -42,7 +49,32 @@ func (h *FeedHandler) handleCreateFeed(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create feed (business logic)
-	feed, err := h.FeedService.CreateAndFollowFeed(r.Context(), params.URL, user.(db.User).ID)
+	feed, err := h.FeedService.CreateAndFollowFeed(r.Context(), params.URL, user.(db.User).ID, params.Private, params.Credentials)
+	var candidatesErr *service.FeedCandidatesError
+	if errors.As(err, &candidatesErr) {
+		// The page advertises several feeds; let the client choose one
+		utils.RespondWithJSON(w, http.StatusMultipleChoices, map[string]interface{}{
+			"error":      candidatesErr.Error(),
+			"candidates": candidatesErr.Candidates,
+		})
+		return
+	}
+	if errors.Is(err, service.ErrBlockedByRobots) {
+		utils.RespondWithError(w, http.StatusForbidden, "The site's robots.txt does not allow fetching this feed")
+		return
+	}
+	if errors.Is(err, service.ErrDestinationNotAllowed) {
+		utils.RespondWithError(w, http.StatusBadRequest, "This feed URL points to a host that may not be fetched")
+		return
+	}
+	if errors.Is(err, service.ErrInvalidFeedCredentials) {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrCredentialsKeyMissing) {
+		utils.RespondWithError(w, http.StatusNotImplemented, "Feeds with credentials are not enabled on this server")
+		return
+	}
 	if err != nil {
 		utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create feed: %v", err))
 		return
@@ -62,7 +94,13 @@ func (h *FeedHandler) handleGetFeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	feed, err := h.FeedService.GetFeedByURL(r.Context(), params.URL)
+	user := r.Context().Value(userContextKey)
+	if user == nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	feed, err := h.FeedService.GetFeedByURL(r.Context(), params.URL, user.(db.User).ID)
 	if err != nil {
 		utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get feeds: %v", err))
 		return
@@ -73,7 +111,14 @@ func (h *FeedHandler) handleGetFeed(w http.ResponseWriter, r *http.Request) {
 
 
 func (h *FeedHandler) handleGetFeeds(w http.ResponseWriter, r *http.Request) {
-	feeds, err := h.FeedService.GetAllFeeds(r.Context())
+	user := r.Context().Value(userContextKey)
+	if user == nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Private feeds of other users are left out
+	feeds, err := h.FeedService.GetAllFeeds(r.Context(), user.(db.User).ID)
 	if err != nil {
 		utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get feeds: %v", err))
 		return
@@ -94,8 +139,18 @@ func (h *FeedHandler) handleUpdateFeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	user := r.Context().Value(userContextKey)
+	if user == nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
 	// Update feed (business logic)
-	err := h.FeedService.UpdateFeed(r.Context(), params.URL)
+	err := h.FeedService.UpdateFeed(r.Context(), params.URL, user.(db.User).ID)
+	if errors.Is(err, service.ErrBlockedByRobots) {
+		utils.RespondWithError(w, http.StatusForbidden, "The site's robots.txt does not allow fetching this feed")
+		return
+	}
 	if err != nil {
 		utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to update feed: %v", err))
 		return
@@ -129,4 +184,115 @@ func (h *FeedHandler) handleFollowFeed(w http.ResponseWriter, r *http.Request) {
 	}
 
 	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Feed followed successfully"})
-}
\ No newline at end of file
+}
+
+// handleGetFeedImage serves the cached logo or favicon of a feed, so clients
+// never have to load images from the feed's own host.
+func (h *FeedHandler) handleGetFeedImage(w http.ResponseWriter, r *http.Request) {
+	feedID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid feed ID")
+		return
+	}
+
+	// Anyone may load the image of a public feed; a private feed's image is
+	// only served to its owner
+	var viewer uuid.NullUUID
+	if user := r.Context().Value(userContextKey); user != nil {
+		viewer = uuid.NullUUID{UUID: user.(db.User).ID, Valid: true}
+	}
+
+	image, private, err := h.FeedService.GetFeedImage(r.Context(), feedID, viewer)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.RespondWithError(w, http.StatusNotFound, "Feed image not found")
+		return
+	}
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get feed image: %v", err))
+		return
+	}
+
+	// The bytes come from a third party, so never let them run as a document
+	w.Header().Set("Content-Type", image.ContentType)
+	if private {
+		// Only the owner may see it, and it may have been fetched with their
+		// credentials, so shared caches must not keep it
+		w.Header().Set("Cache-Control", "private, max-age=86400")
+		w.Header().Set("Vary", "Authorization")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	w.WriteHeader(http.StatusOK)
+	w.Write(image.Data)
+}
+
+func (h *FeedHandler) handleGetFeedHistory(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		URL string `json:"url"`
+	}
+
+	var params parameters
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&params); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	user := r.Context().Value(userContextKey)
+	if user == nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	events, err := h.FeedService.GetFeedHistory(r.Context(), params.URL, user.(db.User).ID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get feed history: %v", err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, events)
+}
+
+// handleSetFeedCredentials replaces the credentials and headers of one of
+// the user's private feeds. They are write-only and never returned.
+func (h *FeedHandler) handleSetFeedCredentials(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		URL         string               `json:"url"`
+		Credentials data.FeedCredentials `json:"credentials"`
+	}
+
+	var params parameters
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&params); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	user := r.Context().Value(userContextKey)
+	if user == nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	err := h.FeedService.SetFeedCredentials(r.Context(), params.URL, user.(db.User).ID, params.Credentials)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, service.ErrNotFeedOwner) {
+		utils.RespondWithError(w, http.StatusNotFound, "No private feed of yours has this URL")
+		return
+	}
+	if errors.Is(err, service.ErrInvalidFeedCredentials) {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrCredentialsKeyMissing) {
+		utils.RespondWithError(w, http.StatusNotImplemented, "Feeds with credentials are not enabled on this server")
+		return
+	}
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to update feed credentials: %v", err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Feed credentials updated successfully"})
+}
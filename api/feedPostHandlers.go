@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"github.com/Rach17/Go-RSS-Aggregator/db"
 	"github.com/Rach17/Go-RSS-Aggregator/service"
 	"github.com/Rach17/Go-RSS-Aggregator/utils"
 )
@@ -41,11 +43,71 @@ func (h *FeedPostHandler) handleGetFeedPost(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Get feed posts (business logic)
-	posts, err := h.FeedPostService.GetFeedPosts(r.Context(), params.URL)
+	posts, err := h.FeedPostService.GetFeedPosts(r.Context(), params.URL, user.(db.User).ID)
 	if err != nil {
 		utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get feed posts: %v", err))
 		return
 	}
 
 	utils.RespondWithJSON(w, http.StatusOK, posts)
-}
\ No newline at end of file
+}
+func (h *FeedPostHandler) handleGetFeedTags(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		URL string `json:"url"`
+	}
+
+	var params parameters
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&params); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	user := r.Context().Value(userContextKey)
+	if user == nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	tags, err := h.FeedPostService.GetFeedTags(r.Context(), params.URL, user.(db.User).ID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get feed tags: %v", err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, tags)
+}
+
+// handleGetFeedPostsByTag returns the posts carrying a tag. The feed URL is
+// optional; without it posts from every feed the user can see are returned.
+func (h *FeedPostHandler) handleGetFeedPostsByTag(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Tag string `json:"tag"`
+		URL string `json:"url"`
+	}
+
+	var params parameters
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&params); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if strings.TrimSpace(params.Tag) == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Tag is required")
+		return
+	}
+
+	user := r.Context().Value(userContextKey)
+	if user == nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	posts, err := h.FeedPostService.GetFeedPostsByTag(r.Context(), params.Tag, params.URL, user.(db.User).ID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get feed posts: %v", err))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, posts)
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/Rach17/Go-RSS-Aggregator/repository" // Importing the repository package for database interactions
 	"github.com/Rach17/Go-RSS-Aggregator/service"    // Importing the service package for business logic
+	"github.com/Rach17/Go-RSS-Aggregator/utils"      // Importing the utils package to parse the credentials key
 
 	"github.com/joho/godotenv" // Importing godotenv to load environment variables from .env file
 	_ "github.com/lib/pq"      // Importing the PostgreSQL driver for database connection (underscore means we don't use it directly)
@@ -46,6 +47,25 @@ func main() {
 	feedService := service.NewFeedService(feedRepo, feedPostRepo)         // Create a new feed service using the feed repository
 	feedPostService := service.NewFeedPostService(feedRepo, feedPostRepo) // Create a new feed post service using the feed and feed post repositories
 
+	// Restrict which hosts and ports user-submitted feed URLs may reach
+	networkPolicy, err := service.NewNetworkPolicy(
+		os.Getenv("FETCH_ALLOW_HOSTS"), os.Getenv("FETCH_DENY_HOSTS"),
+		os.Getenv("FETCH_ALLOW_PORTS"), os.Getenv("FETCH_DENY_PORTS"),
+	)
+	if err != nil {
+		log.Fatalf("Invalid fetch network policy: %v", err) // Log an error if a list cannot be parsed
+	}
+	feedService.NetworkPolicy = networkPolicy
+
+	// Credentials of private feeds are encrypted with this key (base64, 32 bytes)
+	if key := os.Getenv("FEED_CREDENTIALS_KEY"); key != "" {
+		credentialsKey, err := utils.ParseEncryptionKey(key)
+		if err != nil {
+			log.Fatalf("Invalid FEED_CREDENTIALS_KEY: %v", err)
+		}
+		feedService.CredentialsKey = credentialsKey
+	}
+
 	server := NewServer(port, userService, authService, feedService, feedPostService) // Create a new API server with the specified port and services
 	server.Start()
 }
@@ -70,3 +70,16 @@ func (am *AuthMiddleware) authMiddleware(next http.HandlerFunc) http.HandlerFunc
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// optionalAuthMiddleware authenticates the request like authMiddleware when it
+// carries an API key, and lets it through without a user otherwise.
+func (am *AuthMiddleware) optionalAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	authenticated := am.authMiddleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		authenticated.ServeHTTP(w, r)
+	})
+}
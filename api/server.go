@@ -50,7 +50,13 @@ func (s *Server) RegisterHandler() {
 	s.Router.HandleFunc("POST /api/feed", Chain(FeedHandler.handleCreateFeed, AuthMiddleware.authMiddleware, corsMiddleware))
 	s.Router.HandleFunc("GET /api/feed", Chain(FeedHandler.handleGetFeed, AuthMiddleware.authMiddleware, corsMiddleware))
 	s.Router.HandleFunc("GET /api/feeds", Chain(FeedHandler.handleGetFeeds, AuthMiddleware.authMiddleware, corsMiddleware))
+	s.Router.HandleFunc("GET /api/feeds/{id}/image", Chain(FeedHandler.handleGetFeedImage, AuthMiddleware.optionalAuthMiddleware, corsMiddleware))
+	s.Router.HandleFunc("PUT /api/feed/credentials", Chain(FeedHandler.handleSetFeedCredentials, AuthMiddleware.authMiddleware, corsMiddleware))
 	s.Router.HandleFunc("POST /api/following", Chain(FeedHandler.handleFollowFeed, AuthMiddleware.authMiddleware, corsMiddleware))
 
+	s.Router.HandleFunc("GET /api/feed/history", Chain(FeedHandler.handleGetFeedHistory, AuthMiddleware.authMiddleware, corsMiddleware))
+	s.Router.HandleFunc("GET /api/feed/tags", Chain(FeedPostHandler.handleGetFeedTags, AuthMiddleware.authMiddleware, corsMiddleware))
+
 	s.Router.HandleFunc("GET /api/feedposts", Chain(FeedPostHandler.handleGetFeedPost, AuthMiddleware.authMiddleware, corsMiddleware))
+	s.Router.HandleFunc("GET /api/feedposts/tag", Chain(FeedPostHandler.handleGetFeedPostsByTag, AuthMiddleware.authMiddleware, corsMiddleware))
 }
\ No newline at end of file
@@ -0,0 +1,157 @@
+package data
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// AtomFeed is an Atom 1.0 document. Its element fields, like those of the
+// types below, carry the Atom namespace: an unqualified tag matches an element
+// of any namespace, so <content> would also take <media:content> and leave
+// nothing for the embedded Media fields.
+type AtomFeed struct {
+	XMLName  xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    AtomText     `xml:"http://www.w3.org/2005/Atom title"`
+	Subtitle AtomText     `xml:"http://www.w3.org/2005/Atom subtitle"`
+	Links    []AtomLink   `xml:"http://www.w3.org/2005/Atom link"`
+	Language string       `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	XMLBase  string       `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Updated  string       `xml:"http://www.w3.org/2005/Atom updated"`
+	Authors  []AtomPerson `xml:"http://www.w3.org/2005/Atom author"`
+	Icon     string       `xml:"http://www.w3.org/2005/Atom icon"`
+	Logo     string       `xml:"http://www.w3.org/2005/Atom logo"`
+	Entries  []AtomEntry  `xml:"http://www.w3.org/2005/Atom entry"`
+}
+
+type AtomEntry struct {
+	ID         string         `xml:"http://www.w3.org/2005/Atom id"`
+	Title      AtomText       `xml:"http://www.w3.org/2005/Atom title"`
+	Links      []AtomLink     `xml:"http://www.w3.org/2005/Atom link"`
+	Updated    string         `xml:"http://www.w3.org/2005/Atom updated"`
+	Published  string         `xml:"http://www.w3.org/2005/Atom published"`
+	Authors    []AtomPerson   `xml:"http://www.w3.org/2005/Atom author"`
+	Summary    AtomText       `xml:"http://www.w3.org/2005/Atom summary"`
+	Content    AtomText       `xml:"http://www.w3.org/2005/Atom content"`
+	Categories []AtomCategory `xml:"http://www.w3.org/2005/Atom category"`
+	XMLBase    string         `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Media
+}
+
+type AtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+type AtomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type AtomPerson struct {
+	Name  string `xml:"http://www.w3.org/2005/Atom name"`
+	Email string `xml:"http://www.w3.org/2005/Atom email"`
+}
+
+// AtomText holds an Atom text construct. For type="xhtml" the markup lives in
+// child elements, so the inner XML is kept alongside the character data.
+type AtomText struct {
+	Type     string `xml:"type,attr"`
+	Text     string `xml:",chardata"`
+	InnerXML string `xml:",innerxml"`
+}
+
+func (t AtomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.InnerXML)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+// alternateLink returns the link pointing at the HTML version of the resource,
+// falling back to the first link when no rel="alternate" link is present.
+func alternateLink(links []AtomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	if len(links) > 0 {
+		return links[0].Href
+	}
+	return ""
+}
+
+func atomEnclosures(links []AtomLink) []Enclosure {
+	var enclosures []Enclosure
+	for _, link := range links {
+		if link.Rel == "enclosure" && link.Href != "" {
+			enclosures = append(enclosures, Enclosure{URL: link.Href, Type: link.Type, Length: link.Length})
+		}
+	}
+	return enclosures
+}
+
+// categoryTerms returns the term of each category, or its label when the
+// term is missing.
+func categoryTerms(categories []AtomCategory) []string {
+	terms := make([]string, 0, len(categories))
+	for _, category := range categories {
+		if term := firstNonEmpty(category.Term, category.Label); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+func authorNames(authors []AtomPerson) string {
+	names := make([]string, 0, len(authors))
+	for _, author := range authors {
+		if name := strings.TrimSpace(author.Name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// AtomFeedToRSSFeed normalises an Atom document into the RSS shape used by
+// the rest of the application.
+func (f *RSSFeed) AtomFeedToRSSFeed(atom AtomFeed) {
+	f.XMLName = xml.Name{Local: "rss"}
+	f.Channel = Channel{
+		Title:         atom.Title.String(),
+		Description:   atom.Subtitle.String(),
+		Link:          alternateLink(atom.Links),
+		Language:      atom.Language,
+		LastBuildDate: atom.Updated,
+		Image:         FeedImage{URL: strings.TrimSpace(atom.Logo)},
+		IconURL:       strings.TrimSpace(atom.Icon),
+		XMLBase:       atom.XMLBase,
+	}
+
+	feedAuthor := authorNames(atom.Authors)
+	for _, entry := range atom.Entries {
+		publishedAt := entry.Published
+		if publishedAt == "" {
+			publishedAt = entry.Updated
+		}
+		author := authorNames(entry.Authors)
+		if author == "" {
+			author = feedAuthor
+		}
+		f.Channel.Items = append(f.Channel.Items, FeedPost{
+			Title:       entry.Title.String(),
+			Description: entry.Summary.String(),
+			Content:     entry.Content.String(),
+			Link:        alternateLink(entry.Links),
+			PublishedAt: publishedAt,
+			Author:      author,
+			GUID:        strings.TrimSpace(entry.ID),
+			Enclosures:  atomEnclosures(entry.Links),
+			Categories:  categoryTerms(entry.Categories),
+			Media:       entry.Media,
+			XMLBase:     entry.XMLBase,
+		})
+	}
+}
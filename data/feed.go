@@ -1,39 +1,137 @@
 package data
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/xml"
+	"strings"
+
 	"github.com/Rach17/Go-RSS-Aggregator/db"
 )
 
 type RSSFeed struct {
 	XMLName xml.Name `xml:"rss"`
+	XMLBase string   `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
 	Channel Channel  `xml:"channel"`
+	// Truncation records what the parser left out of an oversized document.
+	Truncation Truncation `xml:"-"`
 }
 
 type Channel struct {
-	Title       string `xml:"title"`
-	Description string `xml:"description"`
-	Link        string `xml:"link"`
-	Language    string `xml:"language"`
-	LastBuildDate string `xml:"lastBuildDate"`
-	Items       []FeedPost `xml:"item"`
+	Title         string     `xml:"title"`
+	Description   string     `xml:"description"`
+	Link          string     `xml:"link"`
+	Language      string     `xml:"language"`
+	LastBuildDate string     `xml:"lastBuildDate"`
+	PubDate       string     `xml:"pubDate"`
+	DCDate        string     `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Items         []FeedPost `xml:"item"`
+	// An element goes to the first field that matches it and an unqualified
+	// tag matches any namespace, so ITunesImage has to come before Image or
+	// <itunes:image> would be decoded as the RSS <image>.
+	ITunesImage ITunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	Image       FeedImage   `xml:"image"`
+	TTL         string      `xml:"ttl"`
+	SkipHours   []string    `xml:"skipHours>hour"`
+	SkipDays    []string    `xml:"skipDays>day"`
+	Syndication
+	// IconURL is a small square icon, set from Atom <icon> or JSON Feed favicon.
+	IconURL string `xml:"-"`
+	XMLBase string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
 }
 
 type FeedPost struct {
-	Title       string `xml:"title"`
-	Description string `xml:"description"`
-	Link        string `xml:"link"`
-	PublishedAt string `xml:"pubDate"`
-	Author      string `xml:"author"`
+	Title       string      `xml:"title"`
+	Description string      `xml:"description"`
+	Content     string      `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Link        string      `xml:"link"`
+	PublishedAt string      `xml:"pubDate"`
+	DCDate      string      `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Author      string      `xml:"author"`
+	GUID        string      `xml:"guid"`
+	Enclosures  []Enclosure `xml:"enclosure"`
+	Categories  []string    `xml:"category"`
+	ITunes
+	Media
+	// XMLBase is the item's xml:base as parsed; once URLs have been resolved
+	// it holds the absolute base against which the item's content is resolved.
+	XMLBase string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+}
+
+// Identity returns the key that identifies a post within its feed: the guid
+// when the feed provides one, otherwise the link or enclosure URL, otherwise a
+// hash of the item's content so link-less items do not collide with each other.
+func (p FeedPost) Identity() string {
+	if guid := strings.TrimSpace(p.GUID); guid != "" {
+		return guid
+	}
+	if link := strings.TrimSpace(p.Link); link != "" {
+		return link
+	}
+	if len(p.Enclosures) > 0 && p.Enclosures[0].URL != "" {
+		return p.Enclosures[0].URL
+	}
+	sum := sha256.Sum256([]byte(p.Title + "\x00" + p.PublishedAt + "\x00" + p.Description))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Date returns the item's publication date as written in the feed, taking
+// dc:date when there is no pubDate.
+func (p FeedPost) Date() string {
+	return firstNonEmpty(p.PublishedAt, p.DCDate)
+}
+
+// Date returns the date the feed was last changed as written in the feed.
+func (c Channel) Date() string {
+	return firstNonEmpty(c.LastBuildDate, c.PubDate, c.DCDate)
+}
+
+// maxTagLength bounds the length of a stored tag; longer categories are
+// almost always misused description fields.
+const maxTagLength = 100
+
+// NormalizeTag lower-cases a category and collapses its whitespace, so the
+// same category spelled differently by two publishers maps to one tag.
+func NormalizeTag(category string) string {
+	return strings.ToLower(strings.Join(strings.Fields(category), " "))
+}
+
+// Tags returns the item's categories normalised for storage, without
+// duplicates and in feed order.
+func (p FeedPost) Tags() []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, category := range p.Categories {
+		tag := NormalizeTag(category)
+		if tag == "" || len(tag) > maxTagLength || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// ImageURL returns the feed's logo, preferring the RSS <image> (or Atom
+// <logo>) over the podcast artwork and the icon.
+func (c Channel) ImageURL() string {
+	return firstNonEmpty(c.Image.URL, c.ITunesImage.Href, c.IconURL)
 }
 
 func (f *RSSFeed) DbFeedToRSSFeed(feed db.Feed) {
 	f.XMLName = xml.Name{Local: "rss"}
 	f.Channel = Channel{
-		Title:       feed.Title,
-		Description: feed.Description.String,
-		Link:        feed.Url,
-		Language:    feed.Language,
+		Title:         feed.Title,
+		Description:   feed.Description.String,
+		Link:          feed.Url,
+		Language:      feed.Language,
 		LastBuildDate: feed.LastFetchedAt.Time.Format("Mon, 02 Jan 2006 15:04:05 MST"),
 	}
 }
+
+// FeedCandidate is a feed advertised by a web page during autodiscovery.
+type FeedCandidate struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+	Type  string `json:"type"`
+}
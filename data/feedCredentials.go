@@ -0,0 +1,93 @@
+package data
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// reservedHeaders are set by the fetcher or the HTTP client and cannot be
+// overridden by a feed's custom headers.
+var reservedHeaders = map[string]bool{
+	"Accept":            true,
+	"Authorization":     true,
+	"Connection":        true,
+	"Content-Length":    true,
+	"Host":              true,
+	"If-Modified-Since": true,
+	"If-None-Match":     true,
+	"Te":                true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+	"User-Agent":        true,
+}
+
+// FeedCredentials are what a private feed is fetched with: HTTP Basic
+// credentials or a bearer token, and any custom headers. They are stored
+// encrypted and never returned by the API.
+type FeedCredentials struct {
+	Username    string            `json:"username,omitempty"`
+	Password    string            `json:"password,omitempty"`
+	BearerToken string            `json:"bearer_token,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+// Empty reports whether no credentials or headers are set.
+func (c FeedCredentials) Empty() bool {
+	return c.Username == "" && c.Password == "" && c.BearerToken == "" && len(c.Headers) == 0
+}
+
+// Validate checks that at most one kind of authentication is given and that
+// the custom headers are valid and do not replace the fetcher's own.
+func (c FeedCredentials) Validate() error {
+	if c.BearerToken != "" && (c.Username != "" || c.Password != "") {
+		return fmt.Errorf("use either a username and password or a bearer token, not both")
+	}
+	if c.Password != "" && c.Username == "" {
+		return fmt.Errorf("a password requires a username")
+	}
+	if strings.ContainsAny(c.BearerToken, "\r\n") {
+		return fmt.Errorf("invalid bearer token")
+	}
+	for name, value := range c.Headers {
+		if !validHeaderName(name) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		if reservedHeaders[http.CanonicalHeaderKey(name)] {
+			return fmt.Errorf("header %s cannot be set", http.CanonicalHeaderKey(name))
+		}
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return fmt.Errorf("invalid value for header %s", name)
+		}
+	}
+	return nil
+}
+
+// Apply adds the credentials and headers to req.
+func (c FeedCredentials) Apply(req *http.Request) {
+	for name, value := range c.Headers {
+		req.Header.Set(name, value)
+	}
+	if c.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.BearerToken)
+	} else if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+}
+
+// validHeaderName reports whether name is an HTTP token (RFC 9110).
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			continue
+		}
+		if !strings.ContainsRune("!#$%&'*+-.^_`|~", r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,18 @@
+package data
+
+// Types of the events recorded in a feed's history.
+const (
+	FeedEventTitleChanged       = "title_changed"
+	FeedEventDescriptionChanged = "description_changed"
+	FeedEventLanguageChanged    = "language_changed"
+	FeedEventSiteURLChanged     = "site_url_changed"
+	FeedEventImageChanged       = "image_changed"
+	FeedEventURLMoved           = "url_moved"
+)
+
+// FeedEvent is a change to a feed, recorded in its history.
+type FeedEvent struct {
+	Type     string
+	OldValue string
+	NewValue string
+}
@@ -0,0 +1,21 @@
+package data
+
+import "github.com/Rach17/Go-RSS-Aggregator/db"
+
+// Sources of a stored post's published_at.
+const (
+	// PublishedAtItem is the date given by the item itself.
+	PublishedAtItem = "item"
+	// PublishedAtFeed is the feed's lastBuildDate, used for undated items.
+	PublishedAtFeed = "feed"
+	// PublishedAtFirstSeen is the time the post was first fetched.
+	PublishedAtFirstSeen = "first_seen"
+)
+
+// FeedPostDetails is a stored post together with its related records, as
+// returned by the feed-post API.
+type FeedPostDetails struct {
+	db.FeedPost
+	Enclosures []db.FeedPostEnclosure `json:"enclosures"`
+	Tags       []string               `json:"tags"`
+}
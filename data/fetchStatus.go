@@ -0,0 +1,16 @@
+package data
+
+// Fetch statuses of a feed, as stored in feeds.fetch_status.
+const (
+	// FetchStatusOK means the last fetch of the feed succeeded.
+	FetchStatusOK = "ok"
+	// FetchStatusError means recent fetches failed and the feed is being
+	// retried with backoff.
+	FetchStatusError = "error"
+	// FetchStatusDisabled means the feed failed too many times in a row and
+	// is no longer fetched.
+	FetchStatusDisabled = "disabled"
+	// FetchStatusRobotsBlocked means the site's robots.txt does not allow
+	// the feed to be fetched. It is checked again once the rules expire.
+	FetchStatusRobotsBlocked = "robots_blocked"
+)
@@ -0,0 +1,124 @@
+package data
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// JSONFeed is a JSON Feed 1.0/1.1 document (https://jsonfeed.org/version/1.1).
+type JSONFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	FeedURL     string           `json:"feed_url"`
+	Description string           `json:"description"`
+	Language    string           `json:"language"`
+	Icon        string           `json:"icon"`
+	Favicon     string           `json:"favicon"`
+	Authors     []JSONFeedAuthor `json:"authors"`
+	Author      *JSONFeedAuthor  `json:"author"` // deprecated in 1.1
+	Items       []JSONFeedItem   `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            string               `json:"id"`
+	URL           string               `json:"url"`
+	ExternalURL   string               `json:"external_url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary"`
+	Image         string               `json:"image"`
+	BannerImage   string               `json:"banner_image"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Authors       []JSONFeedAuthor     `json:"authors"`
+	Author        *JSONFeedAuthor      `json:"author"` // deprecated in 1.1
+	Attachments   []JSONFeedAttachment `json:"attachments"`
+	Tags          []string             `json:"tags"`
+}
+
+type JSONFeedAttachment struct {
+	URL               string  `json:"url"`
+	MimeType          string  `json:"mime_type"`
+	Title             string  `json:"title"`
+	SizeInBytes       int64   `json:"size_in_bytes"`
+	DurationInSeconds float64 `json:"duration_in_seconds"`
+}
+
+type JSONFeedAuthor struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Avatar string `json:"avatar"`
+}
+
+func jsonFeedAuthorNames(authors []JSONFeedAuthor, legacy *JSONFeedAuthor) string {
+	if legacy != nil {
+		authors = append(authors, *legacy)
+	}
+	names := make([]string, 0, len(authors))
+	for _, author := range authors {
+		if name := strings.TrimSpace(author.Name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// JSONFeedToRSSFeed normalises a JSON Feed document into the RSS shape used
+// by the rest of the application.
+func (f *RSSFeed) JSONFeedToRSSFeed(jsonFeed JSONFeed) {
+	f.XMLName = xml.Name{Local: "rss"}
+	f.Channel = Channel{
+		Title:       strings.TrimSpace(jsonFeed.Title),
+		Description: strings.TrimSpace(jsonFeed.Description),
+		Link:        jsonFeed.HomePageURL,
+		Language:    jsonFeed.Language,
+		Image:       FeedImage{URL: strings.TrimSpace(jsonFeed.Icon)},
+		IconURL:     strings.TrimSpace(jsonFeed.Favicon),
+	}
+
+	feedAuthor := jsonFeedAuthorNames(jsonFeed.Authors, jsonFeed.Author)
+	for _, item := range jsonFeed.Items {
+		author := jsonFeedAuthorNames(item.Authors, item.Author)
+		if author == "" {
+			author = feedAuthor
+		}
+		var enclosures []Enclosure
+		var duration string
+		for _, attachment := range item.Attachments {
+			enclosure := Enclosure{URL: attachment.URL, Type: attachment.MimeType}
+			if attachment.SizeInBytes > 0 {
+				enclosure.Length = strconv.FormatInt(attachment.SizeInBytes, 10)
+			}
+			enclosures = append(enclosures, enclosure)
+			if duration == "" && attachment.DurationInSeconds > 0 {
+				duration = strconv.FormatFloat(attachment.DurationInSeconds, 'f', -1, 64)
+			}
+		}
+		f.Channel.Items = append(f.Channel.Items, FeedPost{
+			Title:       strings.TrimSpace(item.Title),
+			Description: strings.TrimSpace(item.Summary),
+			Content:     firstNonEmpty(item.ContentHTML, item.ContentText),
+			Link:        firstNonEmpty(item.URL, item.ExternalURL),
+			PublishedAt: firstNonEmpty(item.DatePublished, item.DateModified),
+			Author:      author,
+			GUID:        strings.TrimSpace(item.ID),
+			Enclosures:  enclosures,
+			Categories:  item.Tags,
+			Media:       jsonFeedMedia(item),
+			ITunes:      ITunes{ITunesDuration: duration},
+		})
+	}
+}
+
+func jsonFeedMedia(item JSONFeedItem) Media {
+	var media Media
+	for _, image := range []string{item.Image, item.BannerImage} {
+		if image = strings.TrimSpace(image); image != "" {
+			media.MediaThumbnails = append(media.MediaThumbnails, MediaThumbnail{URL: image})
+		}
+	}
+	return media
+}
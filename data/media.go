@@ -0,0 +1,61 @@
+package data
+
+import "strings"
+
+// MediaThumbnail and MediaContent are elements of the Media RSS namespace
+// (http://search.yahoo.com/mrss/).
+type MediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+type MediaContent struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Medium string `xml:"medium,attr"`
+}
+
+type MediaGroup struct {
+	Thumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	Contents   []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+}
+
+// Media holds the item-level Media RSS elements, which may appear directly on
+// the item or wrapped in a media:group.
+type Media struct {
+	MediaThumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaContents   []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaGroups     []MediaGroup     `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+func (c MediaContent) isImage() bool {
+	return c.Medium == "image" || strings.HasPrefix(c.Type, "image/")
+}
+
+// ThumbnailURL returns the best image to show for an item: a media:thumbnail,
+// then an image media:content, then the episode's itunes:image.
+func (p FeedPost) ThumbnailURL() string {
+	thumbnails := p.MediaThumbnails
+	contents := p.MediaContents
+	for _, group := range p.MediaGroups {
+		thumbnails = append(thumbnails, group.Thumbnails...)
+		contents = append(contents, group.Contents...)
+	}
+	for _, thumbnail := range thumbnails {
+		if thumbnail.URL != "" {
+			return thumbnail.URL
+		}
+	}
+	for _, content := range contents {
+		if content.URL != "" && content.isImage() {
+			return content.URL
+		}
+	}
+	return p.ITunesImage.Href
+}
+
+// FeedImage is the RSS 2.0 channel <image> element.
+type FeedImage struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
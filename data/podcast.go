@@ -0,0 +1,82 @@
+package data
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Enclosure is a media file attached to a post, such as a podcast episode.
+type Enclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// LengthBytes returns the enclosure size in bytes, if the feed gave a valid one.
+func (e Enclosure) LengthBytes() (int64, bool) {
+	length, err := strconv.ParseInt(strings.TrimSpace(e.Length), 10, 64)
+	if err != nil || length <= 0 {
+		return 0, false
+	}
+	return length, true
+}
+
+// ITunes holds the item-level elements of Apple's podcast namespace.
+type ITunes struct {
+	ITunesDuration string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ITunesImage    ITunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	ITunesEpisode  string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+	ITunesSeason   string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd season"`
+	ITunesExplicit string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd explicit"`
+}
+
+type ITunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+// DurationSeconds parses itunes:duration, which may be given as seconds or as
+// MM:SS or HH:MM:SS.
+func (i ITunes) DurationSeconds() (int, bool) {
+	value := strings.TrimSpace(i.ITunesDuration)
+	if value == "" {
+		return 0, false
+	}
+	total := 0.0
+	for _, part := range strings.Split(value, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		total = total*60 + n
+	}
+	return int(math.Round(total)), true
+}
+
+func (i ITunes) Episode() (int, bool) {
+	return parsePositiveInt(i.ITunesEpisode)
+}
+
+func (i ITunes) Season() (int, bool) {
+	return parsePositiveInt(i.ITunesSeason)
+}
+
+// Explicit parses itunes:explicit, which feeds write as yes/no, true/false or
+// explicit/clean.
+func (i ITunes) Explicit() (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(i.ITunesExplicit)) {
+	case "yes", "true", "explicit":
+		return true, true
+	case "no", "false", "clean":
+		return false, true
+	}
+	return false, false
+}
+
+func parsePositiveInt(value string) (int, bool) {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
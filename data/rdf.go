@@ -0,0 +1,81 @@
+package data
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// RDFFeed is an RSS 1.0 document. Unlike RSS 2.0, items are siblings of the
+// channel element rather than children of it.
+type RDFFeed struct {
+	XMLName xml.Name   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# RDF"`
+	Channel RDFChannel `xml:"channel"`
+	Items   []RDFItem  `xml:"item"`
+}
+
+type RDFChannel struct {
+	About       string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	DublinCore
+	Syndication
+}
+
+type RDFItem struct {
+	About       string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	DublinCore
+}
+
+// DublinCore holds the Dublin Core elements commonly found in RSS 1.0 feeds.
+type DublinCore struct {
+	DCTitle       string   `xml:"http://purl.org/dc/elements/1.1/ title"`
+	DCCreator     []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	DCDate        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	DCDescription string   `xml:"http://purl.org/dc/elements/1.1/ description"`
+	DCLanguage    string   `xml:"http://purl.org/dc/elements/1.1/ language"`
+	DCPublisher   string   `xml:"http://purl.org/dc/elements/1.1/ publisher"`
+	DCRights      string   `xml:"http://purl.org/dc/elements/1.1/ rights"`
+	DCSubject     []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+}
+
+// firstNonEmpty returns the first of values that is not blank.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// RDFFeedToRSSFeed normalises an RSS 1.0 document into the RSS shape used by
+// the rest of the application.
+func (f *RSSFeed) RDFFeedToRSSFeed(rdf RDFFeed) {
+	f.XMLName = xml.Name{Local: "rss"}
+	f.Channel = Channel{
+		Title:         firstNonEmpty(rdf.Channel.Title, rdf.Channel.DCTitle),
+		Description:   firstNonEmpty(rdf.Channel.Description, rdf.Channel.DCDescription),
+		Link:          firstNonEmpty(rdf.Channel.Link, rdf.Channel.About),
+		Language:      strings.TrimSpace(rdf.Channel.DCLanguage),
+		LastBuildDate: strings.TrimSpace(rdf.Channel.DCDate),
+		Syndication:   rdf.Channel.Syndication,
+	}
+
+	for _, item := range rdf.Items {
+		f.Channel.Items = append(f.Channel.Items, FeedPost{
+			Title:       firstNonEmpty(item.Title, item.DCTitle),
+			Description: firstNonEmpty(item.Description, item.DCDescription),
+			Content:     strings.TrimSpace(item.Content),
+			Link:        firstNonEmpty(item.Link, item.About),
+			PublishedAt: strings.TrimSpace(item.DCDate),
+			Author:      strings.Join(item.DCCreator, ", "),
+			GUID:        strings.TrimSpace(item.About),
+			Categories:  item.DCSubject,
+		})
+	}
+}
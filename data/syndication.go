@@ -0,0 +1,86 @@
+package data
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Syndication holds the RSS syndication module elements, which tell readers
+// how often a feed is updated.
+type Syndication struct {
+	SyUpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	SyUpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+}
+
+var updatePeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+// UpdatePeriod returns the time between updates given by sy:updatePeriod
+// divided by sy:updateFrequency, which defaults to 1.
+func (s Syndication) UpdatePeriod() (time.Duration, bool) {
+	period, ok := updatePeriods[strings.ToLower(strings.TrimSpace(s.SyUpdatePeriod))]
+	if !ok {
+		return 0, false
+	}
+	frequency := 1
+	if s.SyUpdateFrequency != "" {
+		var ok bool
+		if frequency, ok = parsePositiveInt(s.SyUpdateFrequency); !ok {
+			frequency = 1
+		}
+	}
+	return period / time.Duration(frequency), true
+}
+
+// TTLDuration returns the RSS <ttl>, the number of minutes the feed may be
+// cached before it is fetched again.
+func (c Channel) TTLDuration() (time.Duration, bool) {
+	minutes, ok := parsePositiveInt(c.TTL)
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(minutes) * time.Minute, true
+}
+
+// ParseSkipHours reads <skipHours> <hour> values, hours of the day in GMT
+// during which the feed should not be fetched. Invalid values are ignored.
+func ParseSkipHours(values []string) []int {
+	var hours []int
+	seen := make(map[int]bool)
+	for _, value := range values {
+		hour, err := strconv.Atoi(strings.TrimSpace(value))
+		// Some feeds number the hours 1-24, with 24 meaning midnight
+		if hour == 24 {
+			hour = 0
+		}
+		if err != nil || hour < 0 || hour > 23 || seen[hour] {
+			continue
+		}
+		seen[hour] = true
+		hours = append(hours, hour)
+	}
+	return hours
+}
+
+// ParseSkipDays reads <skipDays> <day> values, days of the week in GMT on
+// which the feed should not be fetched. Invalid values are ignored.
+func ParseSkipDays(values []string) []time.Weekday {
+	var days []time.Weekday
+	seen := make(map[time.Weekday]bool)
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if strings.EqualFold(value, day.String()) && !seen[day] {
+				seen[day] = true
+				days = append(days, day)
+			}
+		}
+	}
+	return days
+}
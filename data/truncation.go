@@ -0,0 +1,37 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Truncation describes what was left out of a feed document that broke the
+// size limits. The zero value means the whole document was read.
+type Truncation struct {
+	// BodyLimit is the size in bytes the body was cut at, or 0 if the body
+	// was read in full.
+	BodyLimit int64
+	// ItemLimit is the maximum number of items kept from a document and
+	// SkippedItems the number of items dropped beyond it.
+	ItemLimit    int
+	SkippedItems int
+}
+
+// Truncated reports whether any part of the document was left out.
+func (t Truncation) Truncated() bool {
+	return t.BodyLimit > 0 || t.SkippedItems > 0
+}
+
+func (t Truncation) String() string {
+	var parts []string
+	if t.BodyLimit > 0 {
+		parts = append(parts, fmt.Sprintf("body cut at %d bytes", t.BodyLimit))
+	}
+	if t.SkippedItems > 0 {
+		parts = append(parts, fmt.Sprintf("%d items beyond the limit of %d skipped", t.SkippedItems, t.ItemLimit))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "feed truncated: " + strings.Join(parts, ", ")
+}
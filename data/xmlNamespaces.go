@@ -0,0 +1,139 @@
+package data
+
+import (
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// ownElements describes which child elements a type decodes. An unqualified
+// field tag such as `xml:"title"` matches <title> in any namespace, and
+// encoding/xml has no way to ask for an element in no namespace, so
+// <atom:link> would replace an RSS <link> and <itunes:title> its <title>.
+// Types whose own elements are unqualified decode through decode, which
+// drops such foreign elements before encoding/xml sees them.
+type ownElements struct {
+	// unqualified are the local names of the fields without a namespace.
+	unqualified map[string]bool
+	// qualified are the names of the fields with a namespace, which are kept
+	// even when they share a local name with an unqualified field.
+	qualified map[xml.Name]bool
+}
+
+// ownElementsOf reads the element names of the fields of struct type t,
+// including those of its embedded structs.
+func ownElementsOf(t reflect.Type) ownElements {
+	elements := ownElements{unqualified: make(map[string]bool), qualified: make(map[xml.Name]bool)}
+	elements.add(t)
+	return elements
+}
+
+func (e ownElements) add(t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("xml")
+		if !ok && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			e.add(field.Type)
+			continue
+		}
+		name, flags, _ := strings.Cut(tag, ",")
+		if !ok || name == "-" || flags != "" || !field.IsExported() {
+			continue
+		}
+		// Only the first element of a path such as skipHours>hour is a child
+		name, _, _ = strings.Cut(name, ">")
+		if space, local, found := strings.Cut(name, " "); found {
+			e.qualified[xml.Name{Space: space, Local: local}] = true
+		} else {
+			e.unqualified[name] = true
+		}
+	}
+}
+
+// foreign reports whether child, an element inside an element in namespace
+// space, is in another namespace and would be taken by an unqualified field.
+func (e ownElements) foreign(space string, child xml.Name) bool {
+	return child.Space != space && e.unqualified[child.Local] && !e.qualified[child]
+}
+
+// decode decodes the element that starts with start into v, leaving out the
+// child elements that are foreign to it.
+func (e ownElements) decode(d *xml.Decoder, start xml.StartElement, v any) error {
+	return xml.NewTokenDecoder(&ownElementReader{decoder: d, start: &start, elements: e}).Decode(v)
+}
+
+// ownElementReader passes on the tokens of one element, skipping its foreign
+// children.
+type ownElementReader struct {
+	decoder  *xml.Decoder
+	start    *xml.StartElement
+	space    string
+	depth    int
+	elements ownElements
+}
+
+func (r *ownElementReader) Token() (xml.Token, error) {
+	if r.start != nil {
+		start := *r.start
+		r.start, r.space, r.depth = nil, start.Name.Space, 1
+		return start, nil
+	}
+	if r.depth == 0 {
+		return nil, io.EOF
+	}
+	for {
+		token, err := r.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if r.depth == 1 && r.elements.foreign(r.space, t.Name) {
+				if err := r.decoder.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			r.depth++
+		case xml.EndElement:
+			r.depth--
+		}
+		return xml.CopyToken(token), nil
+	}
+}
+
+var (
+	channelElements    = ownElementsOf(reflect.TypeFor[Channel]())
+	feedPostElements   = ownElementsOf(reflect.TypeFor[FeedPost]())
+	rdfChannelElements = ownElementsOf(reflect.TypeFor[RDFChannel]())
+	rdfItemElements    = ownElementsOf(reflect.TypeFor[RDFItem]())
+)
+
+// UnmarshalXML decodes an RSS <channel>, ignoring elements of other
+// namespaces that share a name with an RSS element.
+func (c *Channel) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain Channel
+	return channelElements.decode(d, start, (*plain)(c))
+}
+
+// UnmarshalXML decodes an RSS <item>, ignoring elements of other namespaces
+// that share a name with an RSS element.
+func (p *FeedPost) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain FeedPost
+	return feedPostElements.decode(d, start, (*plain)(p))
+}
+
+// UnmarshalXML decodes an RSS 1.0 <channel>, ignoring elements of other
+// namespaces that share a name with an RSS 1.0 element.
+func (c *RDFChannel) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain RDFChannel
+	return rdfChannelElements.decode(d, start, (*plain)(c))
+}
+
+// UnmarshalXML decodes an RSS 1.0 <item>, ignoring elements of other
+// namespaces that share a name with an RSS 1.0 element.
+func (i *RDFItem) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain RDFItem
+	return rdfItemElements.decode(d, start, (*plain)(i))
+}
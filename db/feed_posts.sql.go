@@ -13,36 +13,94 @@ import (
 	"github.com/google/uuid"
 )
 
-const createFeedPost = `-- name: CreateFeedPost :exec
-insert into feed_posts (feed_id, title, url, description,author, published_at)
-values ($1, $2, $3, $4, $5, $6)
+const createFeedPost = `-- name: CreateFeedPost :one
+insert into feed_posts (feed_id, title, url, description,author, published_at, guid, content, description_raw, content_raw,
+    itunes_duration_seconds, itunes_image, itunes_episode, itunes_season, itunes_explicit, thumbnail_url, published_at_source, base_url)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+on conflict (feed_id, guid) do nothing
+returning id
 `
 
 type CreateFeedPostParams struct {
-	FeedID      uuid.UUID      `json:"feed_id"`
-	Title       string         `json:"title"`
-	Url         string         `json:"url"`
-	Description sql.NullString `json:"description"`
-	Author      sql.NullString `json:"author"`
-	PublishedAt time.Time      `json:"published_at"`
+	FeedID                uuid.UUID      `json:"feed_id"`
+	Title                 string         `json:"title"`
+	Url                   string         `json:"url"`
+	Description           sql.NullString `json:"description"`
+	Author                sql.NullString `json:"author"`
+	PublishedAt           time.Time      `json:"published_at"`
+	Guid                  string         `json:"guid"`
+	Content               sql.NullString `json:"content"`
+	DescriptionRaw        sql.NullString `json:"description_raw"`
+	ContentRaw            sql.NullString `json:"content_raw"`
+	ItunesDurationSeconds sql.NullInt32  `json:"itunes_duration_seconds"`
+	ItunesImage           sql.NullString `json:"itunes_image"`
+	ItunesEpisode         sql.NullInt32  `json:"itunes_episode"`
+	ItunesSeason          sql.NullInt32  `json:"itunes_season"`
+	ItunesExplicit        sql.NullBool   `json:"itunes_explicit"`
+	ThumbnailUrl          sql.NullString `json:"thumbnail_url"`
+	PublishedAtSource     sql.NullString `json:"published_at_source"`
+	BaseUrl               sql.NullString `json:"base_url"`
 }
 
-// description: Create a new feed post
-func (q *Queries) CreateFeedPost(ctx context.Context, arg CreateFeedPostParams) error {
-	_, err := q.db.ExecContext(ctx, createFeedPost,
+// description: Create a new feed post, skipping items the feed already has
+func (q *Queries) CreateFeedPost(ctx context.Context, arg CreateFeedPostParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, createFeedPost,
 		arg.FeedID,
 		arg.Title,
 		arg.Url,
 		arg.Description,
 		arg.Author,
 		arg.PublishedAt,
+		arg.Guid,
+		arg.Content,
+		arg.DescriptionRaw,
+		arg.ContentRaw,
+		arg.ItunesDurationSeconds,
+		arg.ItunesImage,
+		arg.ItunesEpisode,
+		arg.ItunesSeason,
+		arg.ItunesExplicit,
+		arg.ThumbnailUrl,
+		arg.PublishedAtSource,
+		arg.BaseUrl,
 	)
-	return err
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getFeedPostGuids = `-- name: GetFeedPostGuids :many
+select guid from feed_posts
+where feed_id = $1
+`
+
+func (q *Queries) GetFeedPostGuids(ctx context.Context, feedID uuid.UUID) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedPostGuids, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var guid string
+		if err := rows.Scan(&guid); err != nil {
+			return nil, err
+		}
+		items = append(items, guid)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const getFeedPosts = `-- name: GetFeedPosts :many
-select feeds.id, feeds.created_at, feeds.updated_at, feeds.title, feeds.url, feeds.description, feeds.language, feeds.last_fetched_at, feed_posts.id, feed_posts.created_at, feed_posts.updated_at, feed_posts.feed_id, feed_posts.title, feed_posts.url, feed_posts.description, feed_posts.published_at, feed_posts.author from feed_posts, feeds
-where feed_posts.url = $1 and feed_posts.feed_id = feeds.id
+select feeds.id, feeds.created_at, feeds.updated_at, feeds.title, feeds.url, feeds.description, feeds.language, feeds.last_fetched_at, feeds.etag, feeds.last_modified, feeds.site_url, feeds.image_url, feeds.consecutive_errors, feeds.last_error, feeds.last_success_at, feeds.next_retry_at, feeds.fetch_status, feeds.next_fetch_at, feeds.ttl_minutes, feeds.update_period_minutes, feeds.skip_hours, feeds.skip_days, feeds.fetch_interval_seconds, feeds.lease_owner, feeds.lease_expires_at, feeds.fetch_warning, feeds.owner_user_id, feed_posts.id, feed_posts.created_at, feed_posts.updated_at, feed_posts.feed_id, feed_posts.title, feed_posts.url, feed_posts.description, feed_posts.published_at, feed_posts.author, feed_posts.guid, feed_posts.content, feed_posts.description_raw, feed_posts.content_raw, feed_posts.itunes_duration_seconds, feed_posts.itunes_image, feed_posts.itunes_episode, feed_posts.itunes_season, feed_posts.itunes_explicit, feed_posts.thumbnail_url, feed_posts.published_at_source, feed_posts.base_url from feed_posts, feeds
+where feeds.id = $1 and feed_posts.feed_id = feeds.id
+order by feed_posts.published_at desc
 `
 
 type GetFeedPostsRow struct {
@@ -50,8 +108,8 @@ type GetFeedPostsRow struct {
 	FeedPost FeedPost `json:"feed_post"`
 }
 
-func (q *Queries) GetFeedPosts(ctx context.Context, url string) ([]GetFeedPostsRow, error) {
-	rows, err := q.db.QueryContext(ctx, getFeedPosts, url)
+func (q *Queries) GetFeedPosts(ctx context.Context, id uuid.UUID) ([]GetFeedPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedPosts, id)
 	if err != nil {
 		return nil, err
 	}
@@ -68,6 +126,25 @@ func (q *Queries) GetFeedPosts(ctx context.Context, url string) ([]GetFeedPostsR
 			&i.Feed.Description,
 			&i.Feed.Language,
 			&i.Feed.LastFetchedAt,
+			&i.Feed.Etag,
+			&i.Feed.LastModified,
+			&i.Feed.SiteUrl,
+			&i.Feed.ImageUrl,
+			&i.Feed.ConsecutiveErrors,
+			&i.Feed.LastError,
+			&i.Feed.LastSuccessAt,
+			&i.Feed.NextRetryAt,
+			&i.Feed.FetchStatus,
+			&i.Feed.NextFetchAt,
+			&i.Feed.TtlMinutes,
+			&i.Feed.UpdatePeriodMinutes,
+			&i.Feed.SkipHours,
+			&i.Feed.SkipDays,
+			&i.Feed.FetchIntervalSeconds,
+			&i.Feed.LeaseOwner,
+			&i.Feed.LeaseExpiresAt,
+			&i.Feed.FetchWarning,
+			&i.Feed.OwnerUserID,
 			&i.FeedPost.ID,
 			&i.FeedPost.CreatedAt,
 			&i.FeedPost.UpdatedAt,
@@ -77,6 +154,18 @@ func (q *Queries) GetFeedPosts(ctx context.Context, url string) ([]GetFeedPostsR
 			&i.FeedPost.Description,
 			&i.FeedPost.PublishedAt,
 			&i.FeedPost.Author,
+			&i.FeedPost.Guid,
+			&i.FeedPost.Content,
+			&i.FeedPost.DescriptionRaw,
+			&i.FeedPost.ContentRaw,
+			&i.FeedPost.ItunesDurationSeconds,
+			&i.FeedPost.ItunesImage,
+			&i.FeedPost.ItunesEpisode,
+			&i.FeedPost.ItunesSeason,
+			&i.FeedPost.ItunesExplicit,
+			&i.FeedPost.ThumbnailUrl,
+			&i.FeedPost.PublishedAtSource,
+			&i.FeedPost.BaseUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -91,26 +180,43 @@ func (q *Queries) GetFeedPosts(ctx context.Context, url string) ([]GetFeedPostsR
 	return items, nil
 }
 
-const getFeedPostsUrlAndTitle = `-- name: GetFeedPostsUrlAndTitle :many
-select url, title from feed_posts
-where feed_id = $1
+const getFeedPostsRawContent = `-- name: GetFeedPostsRawContent :many
+select id, url, base_url, description_raw, content_raw from feed_posts
+where id > $1
+order by id
+limit $2
 `
 
-type GetFeedPostsUrlAndTitleRow struct {
-	Url   string `json:"url"`
-	Title string `json:"title"`
+type GetFeedPostsRawContentParams struct {
+	AfterID  uuid.UUID `json:"after_id"`
+	PageSize int32     `json:"page_size"`
+}
+
+type GetFeedPostsRawContentRow struct {
+	ID             uuid.UUID      `json:"id"`
+	Url            string         `json:"url"`
+	BaseUrl        sql.NullString `json:"base_url"`
+	DescriptionRaw sql.NullString `json:"description_raw"`
+	ContentRaw     sql.NullString `json:"content_raw"`
 }
 
-func (q *Queries) GetFeedPostsUrlAndTitle(ctx context.Context, feedID uuid.UUID) ([]GetFeedPostsUrlAndTitleRow, error) {
-	rows, err := q.db.QueryContext(ctx, getFeedPostsUrlAndTitle, feedID)
+// description: Get a page of the raw post content, ordered by ID and starting after after_id
+func (q *Queries) GetFeedPostsRawContent(ctx context.Context, arg GetFeedPostsRawContentParams) ([]GetFeedPostsRawContentRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedPostsRawContent, arg.AfterID, arg.PageSize)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetFeedPostsUrlAndTitleRow
+	var items []GetFeedPostsRawContentRow
 	for rows.Next() {
-		var i GetFeedPostsUrlAndTitleRow
-		if err := rows.Scan(&i.Url, &i.Title); err != nil {
+		var i GetFeedPostsRawContentRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.BaseUrl,
+			&i.DescriptionRaw,
+			&i.ContentRaw,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -123,3 +229,74 @@ func (q *Queries) GetFeedPostsUrlAndTitle(ctx context.Context, feedID uuid.UUID)
 	}
 	return items, nil
 }
+
+const getRecentFeedPostTimes = `-- name: GetRecentFeedPostTimes :many
+select published_at from feed_posts
+where feed_id = $1 and published_at_source = 'item'
+order by published_at desc
+limit $2
+`
+
+type GetRecentFeedPostTimesParams struct {
+	FeedID uuid.UUID `json:"feed_id"`
+	Limit  int32     `json:"limit"`
+}
+
+// description: Get the publication times of a feed's latest dated posts
+func (q *Queries) GetRecentFeedPostTimes(ctx context.Context, arg GetRecentFeedPostTimesParams) ([]time.Time, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentFeedPostTimes, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []time.Time
+	for rows.Next() {
+		var published_at time.Time
+		if err := rows.Scan(&published_at); err != nil {
+			return nil, err
+		}
+		items = append(items, published_at)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveFeedPosts = `-- name: MoveFeedPosts :exec
+update feed_posts
+set feed_id = $1
+where feed_posts.feed_id = $2
+  and feed_posts.guid not in (select existing.guid from feed_posts existing where existing.feed_id = $1)
+`
+
+type MoveFeedPostsParams struct {
+	ToFeedID   uuid.UUID `json:"to_feed_id"`
+	FromFeedID uuid.UUID `json:"from_feed_id"`
+}
+
+// description: Move the posts of one feed to another, leaving behind those the target already has
+func (q *Queries) MoveFeedPosts(ctx context.Context, arg MoveFeedPostsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedPosts, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const updateFeedPostSanitizedContent = `-- name: UpdateFeedPostSanitizedContent :exec
+update feed_posts
+set description = $2, content = $3
+where id = $1
+`
+
+type UpdateFeedPostSanitizedContentParams struct {
+	ID          uuid.UUID      `json:"id"`
+	Description sql.NullString `json:"description"`
+	Content     sql.NullString `json:"content"`
+}
+
+func (q *Queries) UpdateFeedPostSanitizedContent(ctx context.Context, arg UpdateFeedPostSanitizedContentParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedPostSanitizedContent, arg.ID, arg.Description, arg.Content)
+	return err
+}
@@ -8,14 +8,87 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const claimDueFeeds = `-- name: ClaimDueFeeds :many
+UPDATE feeds
+SET lease_owner = $1, lease_expires_at = NOW() + $2::integer * INTERVAL '1 second'
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE fetch_status <> 'disabled' AND next_fetch_at <= NOW()
+      AND (lease_expires_at IS NULL OR lease_expires_at <= NOW())
+    ORDER BY next_fetch_at
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, title, url, description, language, last_fetched_at, etag, last_modified, site_url, image_url, consecutive_errors, last_error, last_success_at, next_retry_at, fetch_status, next_fetch_at, ttl_minutes, update_period_minutes, skip_hours, skip_days, fetch_interval_seconds, lease_owner, lease_expires_at, fetch_warning, owner_user_id
+`
+
+type ClaimDueFeedsParams struct {
+	LeaseOwner   sql.NullString `json:"lease_owner"`
+	LeaseSeconds int32          `json:"lease_seconds"`
+	MaxFeeds     int32          `json:"max_feeds"`
+}
+
+// description: Lease due feeds to a scraper instance, longest overdue first, skipping feeds another instance holds
+func (q *Queries) ClaimDueFeeds(ctx context.Context, arg ClaimDueFeedsParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueFeeds, arg.LeaseOwner, arg.LeaseSeconds, arg.MaxFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.Language,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.SiteUrl,
+			&i.ImageUrl,
+			&i.ConsecutiveErrors,
+			&i.LastError,
+			&i.LastSuccessAt,
+			&i.NextRetryAt,
+			&i.FetchStatus,
+			&i.NextFetchAt,
+			&i.TtlMinutes,
+			&i.UpdatePeriodMinutes,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.FetchIntervalSeconds,
+			&i.LeaseOwner,
+			&i.LeaseExpiresAt,
+			&i.FetchWarning,
+			&i.OwnerUserID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createFeed = `-- name: CreateFeed :one
-INSERT INTO feeds (title, url, description, language)
-VALUES ($1, $2, $3, $4)
-RETURNING id, created_at, updated_at, title, url, description, language, last_fetched_at
+INSERT INTO feeds (title, url, description, language, site_url, image_url, owner_user_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, created_at, updated_at, title, url, description, language, last_fetched_at, etag, last_modified, site_url, image_url, consecutive_errors, last_error, last_success_at, next_retry_at, fetch_status, next_fetch_at, ttl_minutes, update_period_minutes, skip_hours, skip_days, fetch_interval_seconds, lease_owner, lease_expires_at, fetch_warning, owner_user_id
 `
 
 type CreateFeedParams struct {
@@ -23,6 +96,9 @@ type CreateFeedParams struct {
 	Url         string         `json:"url"`
 	Description sql.NullString `json:"description"`
 	Language    string         `json:"language"`
+	SiteUrl     sql.NullString `json:"site_url"`
+	ImageUrl    sql.NullString `json:"image_url"`
+	OwnerUserID uuid.NullUUID  `json:"owner_user_id"`
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -31,6 +107,9 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.Url,
 		arg.Description,
 		arg.Language,
+		arg.SiteUrl,
+		arg.ImageUrl,
+		arg.OwnerUserID,
 	)
 	var i Feed
 	err := row.Scan(
@@ -42,10 +121,39 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Description,
 		&i.Language,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.SiteUrl,
+		&i.ImageUrl,
+		&i.ConsecutiveErrors,
+		&i.LastError,
+		&i.LastSuccessAt,
+		&i.NextRetryAt,
+		&i.FetchStatus,
+		&i.NextFetchAt,
+		&i.TtlMinutes,
+		&i.UpdatePeriodMinutes,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.FetchIntervalSeconds,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.FetchWarning,
+		&i.OwnerUserID,
 	)
 	return i, err
 }
 
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
 const followFeed = `-- name: FollowFeed :exec
 INSERT INTO feed_follow (user_id, feed_id)
 VALUES ($1, $2)
@@ -63,11 +171,13 @@ func (q *Queries) FollowFeed(ctx context.Context, arg FollowFeedParams) error {
 }
 
 const getAllFeeds = `-- name: GetAllFeeds :many
-SELECT id, created_at, updated_at, title, url, description, language, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, title, url, description, language, last_fetched_at, etag, last_modified, site_url, image_url, consecutive_errors, last_error, last_success_at, next_retry_at, fetch_status, next_fetch_at, ttl_minutes, update_period_minutes, skip_hours, skip_days, fetch_interval_seconds, lease_owner, lease_expires_at, fetch_warning, owner_user_id FROM feeds
+WHERE owner_user_id IS NULL OR owner_user_id = $1
 `
 
-func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
-	rows, err := q.db.QueryContext(ctx, getAllFeeds)
+// description: Get the public feeds and the user's own private feeds
+func (q *Queries) GetAllFeeds(ctx context.Context, ownerUserID uuid.NullUUID) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getAllFeeds, ownerUserID)
 	if err != nil {
 		return nil, err
 	}
@@ -84,6 +194,25 @@ func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Description,
 			&i.Language,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.SiteUrl,
+			&i.ImageUrl,
+			&i.ConsecutiveErrors,
+			&i.LastError,
+			&i.LastSuccessAt,
+			&i.NextRetryAt,
+			&i.FetchStatus,
+			&i.NextFetchAt,
+			&i.TtlMinutes,
+			&i.UpdatePeriodMinutes,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.FetchIntervalSeconds,
+			&i.LeaseOwner,
+			&i.LeaseExpiresAt,
+			&i.FetchWarning,
+			&i.OwnerUserID,
 		); err != nil {
 			return nil, err
 		}
@@ -99,7 +228,7 @@ func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
 }
 
 const getFeedByID = `-- name: GetFeedByID :one
-SELECT id, created_at, updated_at, title, url, description, language, last_fetched_at FROM feeds WHERE id = $1
+SELECT id, created_at, updated_at, title, url, description, language, last_fetched_at, etag, last_modified, site_url, image_url, consecutive_errors, last_error, last_success_at, next_retry_at, fetch_status, next_fetch_at, ttl_minutes, update_period_minutes, skip_hours, skip_days, fetch_interval_seconds, lease_owner, lease_expires_at, fetch_warning, owner_user_id FROM feeds WHERE id = $1
 `
 
 func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
@@ -114,14 +243,38 @@ func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
 		&i.Description,
 		&i.Language,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.SiteUrl,
+		&i.ImageUrl,
+		&i.ConsecutiveErrors,
+		&i.LastError,
+		&i.LastSuccessAt,
+		&i.NextRetryAt,
+		&i.FetchStatus,
+		&i.NextFetchAt,
+		&i.TtlMinutes,
+		&i.UpdatePeriodMinutes,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.FetchIntervalSeconds,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.FetchWarning,
+		&i.OwnerUserID,
 	)
 	return i, err
 }
 
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, title, url, description, language, last_fetched_at FROM feeds WHERE url = $1
+SELECT id, created_at, updated_at, title, url, description, language, last_fetched_at, etag, last_modified, site_url, image_url, consecutive_errors, last_error, last_success_at, next_retry_at, fetch_status, next_fetch_at, ttl_minutes, update_period_minutes, skip_hours, skip_days, fetch_interval_seconds, lease_owner, lease_expires_at, fetch_warning, owner_user_id FROM feeds
+WHERE owner_user_id IS NULL
+  AND (url = $1 OR id = (SELECT feed_id FROM feed_aliases WHERE feed_aliases.url = $1))
+ORDER BY url = $1 DESC
+LIMIT 1
 `
 
+// description: Get a public feed by its URL or by a URL it has moved away from
 func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 	row := q.db.QueryRowContext(ctx, getFeedByURL, url)
 	var i Feed
@@ -134,55 +287,248 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.Description,
 		&i.Language,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.SiteUrl,
+		&i.ImageUrl,
+		&i.ConsecutiveErrors,
+		&i.LastError,
+		&i.LastSuccessAt,
+		&i.NextRetryAt,
+		&i.FetchStatus,
+		&i.NextFetchAt,
+		&i.TtlMinutes,
+		&i.UpdatePeriodMinutes,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.FetchIntervalSeconds,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.FetchWarning,
+		&i.OwnerUserID,
 	)
 	return i, err
 }
 
-const getLastFetchedFeeds = `-- name: GetLastFetchedFeeds :many
-SELECT id, created_at, updated_at, title, url, description, language, last_fetched_at FROM feeds
-ORDER BY last_fetched_at DESC
-LIMIT $1
+const getVisibleFeedByURL = `-- name: GetVisibleFeedByURL :one
+SELECT id, created_at, updated_at, title, url, description, language, last_fetched_at, etag, last_modified, site_url, image_url, consecutive_errors, last_error, last_success_at, next_retry_at, fetch_status, next_fetch_at, ttl_minutes, update_period_minutes, skip_hours, skip_days, fetch_interval_seconds, lease_owner, lease_expires_at, fetch_warning, owner_user_id FROM feeds
+WHERE (url = $1 AND owner_user_id = $2)
+   OR (owner_user_id IS NULL
+       AND (url = $1 OR id = (SELECT feed_id FROM feed_aliases WHERE feed_aliases.url = $1)))
+ORDER BY owner_user_id IS NULL, url = $1 DESC
+LIMIT 1
 `
 
-func (q *Queries) GetLastFetchedFeeds(ctx context.Context, limit int32) ([]Feed, error) {
-	rows, err := q.db.QueryContext(ctx, getLastFetchedFeeds, limit)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Feed
-	for rows.Next() {
-		var i Feed
-		if err := rows.Scan(
-			&i.ID,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.Title,
-			&i.Url,
-			&i.Description,
-			&i.Language,
-			&i.LastFetchedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type GetVisibleFeedByURLParams struct {
+	Url    string        `json:"url"`
+	UserID uuid.NullUUID `json:"user_id"`
+}
+
+// description: Get the feed a user sees at a URL: their own private feed, else the public one
+func (q *Queries) GetVisibleFeedByURL(ctx context.Context, arg GetVisibleFeedByURLParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getVisibleFeedByURL, arg.Url, arg.UserID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.Language,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.SiteUrl,
+		&i.ImageUrl,
+		&i.ConsecutiveErrors,
+		&i.LastError,
+		&i.LastSuccessAt,
+		&i.NextRetryAt,
+		&i.FetchStatus,
+		&i.NextFetchAt,
+		&i.TtlMinutes,
+		&i.UpdatePeriodMinutes,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.FetchIntervalSeconds,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.FetchWarning,
+		&i.OwnerUserID,
+	)
+	return i, err
+}
+
+const moveFeedFollows = `-- name: MoveFeedFollows :exec
+INSERT INTO feed_follow (user_id, feed_id)
+SELECT user_id, $1 FROM feed_follow
+WHERE feed_follow.feed_id = $2
+ON CONFLICT (user_id, feed_id) DO NOTHING
+`
+
+type MoveFeedFollowsParams struct {
+	ToFeedID   uuid.UUID `json:"to_feed_id"`
+	FromFeedID uuid.UUID `json:"from_feed_id"`
+}
+
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollows, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const recordFeedFetchFailure = `-- name: RecordFeedFetchFailure :exec
+UPDATE feeds
+SET consecutive_errors = $2, last_error = $3, next_retry_at = $4, fetch_status = $5, next_fetch_at = COALESCE($4, next_fetch_at)
+WHERE id = $1
+`
+
+type RecordFeedFetchFailureParams struct {
+	ID                uuid.UUID      `json:"id"`
+	ConsecutiveErrors int32          `json:"consecutive_errors"`
+	LastError         sql.NullString `json:"last_error"`
+	NextRetryAt       sql.NullTime   `json:"next_retry_at"`
+	FetchStatus       string         `json:"fetch_status"`
+}
+
+func (q *Queries) RecordFeedFetchFailure(ctx context.Context, arg RecordFeedFetchFailureParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedFetchFailure,
+		arg.ID,
+		arg.ConsecutiveErrors,
+		arg.LastError,
+		arg.NextRetryAt,
+		arg.FetchStatus,
+	)
+	return err
+}
+
+const recordFeedFetchSuccess = `-- name: RecordFeedFetchSuccess :exec
+UPDATE feeds
+SET consecutive_errors = 0, last_error = NULL, last_success_at = NOW(), next_retry_at = NULL, fetch_status = 'ok',
+    next_fetch_at = $2, fetch_interval_seconds = $3, fetch_warning = $4
+WHERE id = $1
+`
+
+type RecordFeedFetchSuccessParams struct {
+	ID                   uuid.UUID      `json:"id"`
+	NextFetchAt          time.Time      `json:"next_fetch_at"`
+	FetchIntervalSeconds sql.NullInt32  `json:"fetch_interval_seconds"`
+	FetchWarning         sql.NullString `json:"fetch_warning"`
+}
+
+func (q *Queries) RecordFeedFetchSuccess(ctx context.Context, arg RecordFeedFetchSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedFetchSuccess, arg.ID, arg.NextFetchAt, arg.FetchIntervalSeconds, arg.FetchWarning)
+	return err
+}
+
+const releaseFeedLease = `-- name: ReleaseFeedLease :exec
+UPDATE feeds
+SET lease_owner = NULL, lease_expires_at = NULL
+WHERE id = $1 AND lease_owner = $2
+`
+
+type ReleaseFeedLeaseParams struct {
+	ID         uuid.UUID      `json:"id"`
+	LeaseOwner sql.NullString `json:"lease_owner"`
+}
+
+func (q *Queries) ReleaseFeedLease(ctx context.Context, arg ReleaseFeedLeaseParams) error {
+	_, err := q.db.ExecContext(ctx, releaseFeedLease, arg.ID, arg.LeaseOwner)
+	return err
+}
+
+const updateFeedCacheValidators = `-- name: UpdateFeedCacheValidators :exec
+UPDATE feeds
+SET etag = $2, last_modified = $3
+WHERE id = $1
+`
+
+type UpdateFeedCacheValidatorsParams struct {
+	ID           uuid.UUID      `json:"id"`
+	Etag         sql.NullString `json:"etag"`
+	LastModified sql.NullString `json:"last_modified"`
+}
+
+func (q *Queries) UpdateFeedCacheValidators(ctx context.Context, arg UpdateFeedCacheValidatorsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedCacheValidators, arg.ID, arg.Etag, arg.LastModified)
+	return err
 }
 
 const updateFeedLastFetchedAt = `-- name: UpdateFeedLastFetchedAt :exec
 UPDATE feeds
 SET last_fetched_at = NOW()
-WHERE url = $1
+WHERE id = $1
+`
+
+func (q *Queries) UpdateFeedLastFetchedAt(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, updateFeedLastFetchedAt, id)
+	return err
+}
+
+const updateFeedMetadata = `-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $2, description = $3, language = $4, site_url = $5, image_url = $6, updated_at = NOW()
+WHERE id = $1
 `
 
-func (q *Queries) UpdateFeedLastFetchedAt(ctx context.Context, url string) error {
-	_, err := q.db.ExecContext(ctx, updateFeedLastFetchedAt, url)
+type UpdateFeedMetadataParams struct {
+	ID          uuid.UUID      `json:"id"`
+	Title       string         `json:"title"`
+	Description sql.NullString `json:"description"`
+	Language    string         `json:"language"`
+	SiteUrl     sql.NullString `json:"site_url"`
+	ImageUrl    sql.NullString `json:"image_url"`
+}
+
+func (q *Queries) UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedMetadata,
+		arg.ID,
+		arg.Title,
+		arg.Description,
+		arg.Language,
+		arg.SiteUrl,
+		arg.ImageUrl,
+	)
+	return err
+}
+
+const updateFeedPollHints = `-- name: UpdateFeedPollHints :exec
+UPDATE feeds
+SET ttl_minutes = $2, update_period_minutes = $3, skip_hours = $4, skip_days = $5
+WHERE id = $1
+`
+
+type UpdateFeedPollHintsParams struct {
+	ID                  uuid.UUID      `json:"id"`
+	TtlMinutes          sql.NullInt32  `json:"ttl_minutes"`
+	UpdatePeriodMinutes sql.NullInt32  `json:"update_period_minutes"`
+	SkipHours           sql.NullString `json:"skip_hours"`
+	SkipDays            sql.NullString `json:"skip_days"`
+}
+
+func (q *Queries) UpdateFeedPollHints(ctx context.Context, arg UpdateFeedPollHintsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedPollHints,
+		arg.ID,
+		arg.TtlMinutes,
+		arg.UpdatePeriodMinutes,
+		arg.SkipHours,
+		arg.SkipDays,
+	)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedURLParams struct {
+	ID  uuid.UUID `json:"id"`
+	Url string    `json:"url"`
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedURL, arg.ID, arg.Url)
 	return err
 }
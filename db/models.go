@@ -12,14 +12,55 @@ import (
 )
 
 type Feed struct {
-	ID            uuid.UUID      `json:"id"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     sql.NullTime   `json:"updated_at"`
-	Title         string         `json:"title"`
-	Url           string         `json:"url"`
-	Description   sql.NullString `json:"description"`
-	Language      string         `json:"language"`
-	LastFetchedAt sql.NullTime   `json:"last_fetched_at"`
+	ID                   uuid.UUID      `json:"id"`
+	CreatedAt            time.Time      `json:"created_at"`
+	UpdatedAt            sql.NullTime   `json:"updated_at"`
+	Title                string         `json:"title"`
+	Url                  string         `json:"url"`
+	Description          sql.NullString `json:"description"`
+	Language             string         `json:"language"`
+	LastFetchedAt        sql.NullTime   `json:"last_fetched_at"`
+	Etag                 sql.NullString `json:"etag"`
+	LastModified         sql.NullString `json:"last_modified"`
+	SiteUrl              sql.NullString `json:"site_url"`
+	ImageUrl             sql.NullString `json:"image_url"`
+	ConsecutiveErrors    int32          `json:"consecutive_errors"`
+	LastError            sql.NullString `json:"last_error"`
+	LastSuccessAt        sql.NullTime   `json:"last_success_at"`
+	NextRetryAt          sql.NullTime   `json:"next_retry_at"`
+	FetchStatus          string         `json:"fetch_status"`
+	NextFetchAt          time.Time      `json:"next_fetch_at"`
+	TtlMinutes           sql.NullInt32  `json:"ttl_minutes"`
+	UpdatePeriodMinutes  sql.NullInt32  `json:"update_period_minutes"`
+	SkipHours            sql.NullString `json:"skip_hours"`
+	SkipDays             sql.NullString `json:"skip_days"`
+	FetchIntervalSeconds sql.NullInt32  `json:"fetch_interval_seconds"`
+	LeaseOwner           sql.NullString `json:"lease_owner"`
+	LeaseExpiresAt       sql.NullTime   `json:"lease_expires_at"`
+	FetchWarning         sql.NullString `json:"fetch_warning"`
+	OwnerUserID          uuid.NullUUID  `json:"owner_user_id"`
+}
+
+type FeedAlias struct {
+	Url       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+	FeedID    uuid.UUID `json:"feed_id"`
+}
+
+type FeedCredential struct {
+	FeedID    uuid.UUID    `json:"feed_id"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt sql.NullTime `json:"updated_at"`
+	Encrypted []byte       `json:"encrypted"`
+}
+
+type FeedEvent struct {
+	ID        uuid.UUID      `json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	FeedID    uuid.UUID      `json:"feed_id"`
+	EventType string         `json:"event_type"`
+	OldValue  sql.NullString `json:"old_value"`
+	NewValue  sql.NullString `json:"new_value"`
 }
 
 type FeedFollow struct {
@@ -30,16 +71,57 @@ type FeedFollow struct {
 	FeedID    uuid.UUID    `json:"feed_id"`
 }
 
+type FeedImage struct {
+	FeedID      uuid.UUID    `json:"feed_id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   sql.NullTime `json:"updated_at"`
+	SourceUrl   string       `json:"source_url"`
+	ContentType string       `json:"content_type"`
+	Data        []byte       `json:"data"`
+}
+
 type FeedPost struct {
-	ID          uuid.UUID      `json:"id"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   sql.NullTime   `json:"updated_at"`
-	FeedID      uuid.UUID      `json:"feed_id"`
-	Title       string         `json:"title"`
-	Url         string         `json:"url"`
-	Description sql.NullString `json:"description"`
-	PublishedAt time.Time      `json:"published_at"`
-	Author      sql.NullString `json:"author"`
+	ID                    uuid.UUID      `json:"id"`
+	CreatedAt             time.Time      `json:"created_at"`
+	UpdatedAt             sql.NullTime   `json:"updated_at"`
+	FeedID                uuid.UUID      `json:"feed_id"`
+	Title                 string         `json:"title"`
+	Url                   string         `json:"url"`
+	Description           sql.NullString `json:"description"`
+	PublishedAt           time.Time      `json:"published_at"`
+	Author                sql.NullString `json:"author"`
+	Guid                  string         `json:"guid"`
+	Content               sql.NullString `json:"content"`
+	DescriptionRaw        sql.NullString `json:"description_raw"`
+	ContentRaw            sql.NullString `json:"content_raw"`
+	ItunesDurationSeconds sql.NullInt32  `json:"itunes_duration_seconds"`
+	ItunesImage           sql.NullString `json:"itunes_image"`
+	ItunesEpisode         sql.NullInt32  `json:"itunes_episode"`
+	ItunesSeason          sql.NullInt32  `json:"itunes_season"`
+	ItunesExplicit        sql.NullBool   `json:"itunes_explicit"`
+	ThumbnailUrl          sql.NullString `json:"thumbnail_url"`
+	PublishedAtSource     sql.NullString `json:"published_at_source"`
+	BaseUrl               sql.NullString `json:"base_url"`
+}
+
+type FeedPostEnclosure struct {
+	ID         uuid.UUID      `json:"id"`
+	CreatedAt  time.Time      `json:"created_at"`
+	FeedPostID uuid.UUID      `json:"feed_post_id"`
+	Url        string         `json:"url"`
+	MimeType   sql.NullString `json:"mime_type"`
+	Length     sql.NullInt64  `json:"length"`
+}
+
+type FeedPostTag struct {
+	FeedPostID uuid.UUID `json:"feed_post_id"`
+	TagID      uuid.UUID `json:"tag_id"`
+}
+
+type Tag struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
 }
 
 type User struct {
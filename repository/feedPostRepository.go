@@ -2,17 +2,55 @@ package repository
 
 import (
 	"context"
-	"time"
 	"database/sql"
+	"errors"
+	"time"
+
 	"github.com/Rach17/Go-RSS-Aggregator/db"
 	"github.com/google/uuid"
 )
 
 type FeedPostRepository interface {
-	Create(ctx context.Context, feedID uuid.UUID, title, description, url, author string, publishedAt time.Time) error
-	GetFeedPosts(ctx context.Context, feedURL string) ([]db.FeedPost, error)
-	GetFeedPostsUrlAndTitle(ctx context.Context, feedID uuid.UUID) (map[string]string, error)
+	Create(ctx context.Context, post CreateFeedPostInput) (uuid.UUID, error)
+	CreateEnclosure(ctx context.Context, postID uuid.UUID, url, mimeType string, length int64) error
+	AddTag(ctx context.Context, postID uuid.UUID, name string) error
+	GetFeedEnclosures(ctx context.Context, feedID uuid.UUID) ([]db.FeedPostEnclosure, error)
+	GetFeedPosts(ctx context.Context, feedID uuid.UUID) ([]db.FeedPost, error)
+	GetFeedPostsByTag(ctx context.Context, tag string, feedID uuid.NullUUID, userID uuid.UUID) ([]db.FeedPost, error)
+	GetFeedPostTags(ctx context.Context, feedID uuid.UUID) ([]db.GetFeedPostTagsByFeedIDRow, error)
+	GetFeedTags(ctx context.Context, feedID uuid.UUID) ([]db.GetFeedTagsRow, error)
+	GetFeedPostGuids(ctx context.Context, feedID uuid.UUID) (map[string]bool, error)
+	GetRecentPostTimes(ctx context.Context, feedID uuid.UUID, limit int) ([]time.Time, error)
+	GetFeedPostsRawContent(ctx context.Context, afterID uuid.UUID, pageSize int) ([]db.GetFeedPostsRawContentRow, error)
+	UpdateSanitizedContent(ctx context.Context, postID uuid.UUID, description, content string) error
+}
+
+// CreateFeedPostInput holds the fields of a post to be stored. Description
+// and Content are the sanitised versions of DescriptionRaw and ContentRaw.
+type CreateFeedPostInput struct {
+	FeedID         uuid.UUID
+	Guid           string
+	Title          string
+	Description    string
+	DescriptionRaw string
+	Content        string
+	ContentRaw     string
+	Url            string
+	Author         string
+	PublishedAt    time.Time
+	// PublishedAtSource records where PublishedAt came from, one of the
+	// data.PublishedAt* constants.
+	PublishedAtSource string
+	// BaseURL is the URL relative references in the raw content were
+	// resolved against when Description and Content were sanitised.
+	BaseURL string
 
+	ItunesDurationSeconds sql.NullInt32
+	ItunesImage           string
+	ItunesEpisode         sql.NullInt32
+	ItunesSeason          sql.NullInt32
+	ItunesExplicit        sql.NullBool
+	ThumbnailUrl          string
 }
 
 type DBFeedPostRepository struct {
@@ -27,28 +65,63 @@ func NewDBFeedPostRepository(database *sql.DB) *DBFeedPostRepository {
 	}
 }
 
-func (r *DBFeedPostRepository) Create(ctx context.Context, feedID uuid.UUID, title, description, url, author string, publishedAt time.Time) error {
-	desc := sql.NullString{String: description, Valid: true}
-	if description == "" {
-		desc = sql.NullString{String: "", Valid: false}
-	}
-	auth := sql.NullString{String: author, Valid: true}
-	if author == "" {
-		auth = sql.NullString{String: "", Valid: false}
+// Create stores a post and returns its ID. If the feed already has a post
+// with the same guid nothing is stored and uuid.Nil is returned.
+func (r *DBFeedPostRepository) Create(ctx context.Context, post CreateFeedPostInput) (uuid.UUID, error) {
+	id, err := r.queries.CreateFeedPost(ctx, db.CreateFeedPostParams{
+		FeedID:         post.FeedID,
+		Title:          post.Title,
+		Description:    nullString(post.Description),
+		DescriptionRaw: nullString(post.DescriptionRaw),
+		Content:        nullString(post.Content),
+		ContentRaw:     nullString(post.ContentRaw),
+		Url:            post.Url,
+		PublishedAt:    post.PublishedAt,
+		Author:         nullString(post.Author),
+		Guid:           post.Guid,
+
+		ItunesDurationSeconds: post.ItunesDurationSeconds,
+		ItunesImage:           nullString(post.ItunesImage),
+		ItunesEpisode:         post.ItunesEpisode,
+		ItunesSeason:          post.ItunesSeason,
+		ItunesExplicit:        post.ItunesExplicit,
+		ThumbnailUrl:          nullString(post.ThumbnailUrl),
+		PublishedAtSource:     nullString(post.PublishedAtSource),
+		BaseUrl:               nullString(post.BaseURL),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, nil
 	}
-	return r.queries.CreateFeedPost(ctx, db.CreateFeedPostParams{
-		FeedID:      feedID,
-		Title:       title,
-		Description: desc,
+	return id, err
+}
+
+func (r *DBFeedPostRepository) CreateEnclosure(ctx context.Context, postID uuid.UUID, url, mimeType string, length int64) error {
+	return r.queries.CreateFeedPostEnclosure(ctx, db.CreateFeedPostEnclosureParams{
+		FeedPostID: postID,
 		Url:        url,
-		PublishedAt: publishedAt,
-		Author:     auth,
+		MimeType:   nullString(mimeType),
+		Length:     sql.NullInt64{Int64: length, Valid: length > 0},
 	})
 }
 
+// AddTag attaches a tag to a post, creating the tag if it does not exist yet.
+func (r *DBFeedPostRepository) AddTag(ctx context.Context, postID uuid.UUID, name string) error {
+	tagID, err := r.queries.UpsertTag(ctx, name)
+	if err != nil {
+		return err
+	}
+	return r.queries.CreateFeedPostTag(ctx, db.CreateFeedPostTagParams{
+		FeedPostID: postID,
+		TagID:      tagID,
+	})
+}
 
-func (r *DBFeedPostRepository) GetFeedPosts(ctx context.Context, feedURL string) ([]db.FeedPost, error) {
-	posts, err := r.queries.GetFeedPosts(ctx, feedURL)
+func (r *DBFeedPostRepository) GetFeedEnclosures(ctx context.Context, feedID uuid.UUID) ([]db.FeedPostEnclosure, error) {
+	return r.queries.GetFeedPostEnclosuresByFeedID(ctx, feedID)
+}
+
+func (r *DBFeedPostRepository) GetFeedPosts(ctx context.Context, feedID uuid.UUID) ([]db.FeedPost, error) {
+	posts, err := r.queries.GetFeedPosts(ctx, feedID)
 	if err != nil {
 		return nil, err
 	}
@@ -60,15 +133,65 @@ func (r *DBFeedPostRepository) GetFeedPosts(ctx context.Context, feedURL string)
 	return feedPosts, nil
 }
 
-func (r *DBFeedPostRepository) GetFeedPostsUrlAndTitle(ctx context.Context, feedID uuid.UUID) (map[string]string, error) {
-	posts, err := r.queries.GetFeedPostsUrlAndTitle(ctx, feedID)
+// GetFeedPostsByTag returns the posts carrying a tag, across all the feeds
+// userID can see when feedID is null.
+func (r *DBFeedPostRepository) GetFeedPostsByTag(ctx context.Context, tag string, feedID uuid.NullUUID, userID uuid.UUID) ([]db.FeedPost, error) {
+	return r.queries.GetFeedPostsByTag(ctx, db.GetFeedPostsByTagParams{
+		Name:   tag,
+		FeedID: feedID,
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+	})
+}
+
+func (r *DBFeedPostRepository) GetFeedPostTags(ctx context.Context, feedID uuid.UUID) ([]db.GetFeedPostTagsByFeedIDRow, error) {
+	return r.queries.GetFeedPostTagsByFeedID(ctx, feedID)
+}
+
+func (r *DBFeedPostRepository) GetFeedTags(ctx context.Context, feedID uuid.UUID) ([]db.GetFeedTagsRow, error) {
+	return r.queries.GetFeedTags(ctx, feedID)
+}
+
+func (r *DBFeedPostRepository) GetFeedPostGuids(ctx context.Context, feedID uuid.UUID) (map[string]bool, error) {
+	guids, err := r.queries.GetFeedPostGuids(ctx, feedID)
 	if err != nil {
 		return nil, err
 	}
 
-	urlTitleMap := make(map[string]string)
-	for _, post := range posts {
-		urlTitleMap[post.Url] = post.Title
+	guidSet := make(map[string]bool, len(guids))
+	for _, guid := range guids {
+		guidSet[guid] = true
 	}
-	return urlTitleMap, nil
-}
\ No newline at end of file
+	return guidSet, nil
+}
+
+// GetRecentPostTimes returns the publication times of a feed's latest posts
+// that carried their own date, newest first.
+func (r *DBFeedPostRepository) GetRecentPostTimes(ctx context.Context, feedID uuid.UUID, limit int) ([]time.Time, error) {
+	return r.queries.GetRecentFeedPostTimes(ctx, db.GetRecentFeedPostTimesParams{
+		FeedID: feedID,
+		Limit:  int32(limit),
+	})
+}
+
+// GetFeedPostsRawContent returns up to pageSize posts with an ID greater than
+// afterID, in ID order. Pass uuid.Nil for the first page and the last ID of a
+// page for the next.
+func (r *DBFeedPostRepository) GetFeedPostsRawContent(ctx context.Context, afterID uuid.UUID, pageSize int) ([]db.GetFeedPostsRawContentRow, error) {
+	return r.queries.GetFeedPostsRawContent(ctx, db.GetFeedPostsRawContentParams{
+		AfterID:  afterID,
+		PageSize: int32(pageSize),
+	})
+}
+
+func (r *DBFeedPostRepository) UpdateSanitizedContent(ctx context.Context, postID uuid.UUID, description, content string) error {
+	return r.queries.UpdateFeedPostSanitizedContent(ctx, db.UpdateFeedPostSanitizedContentParams{
+		ID:          postID,
+		Description: nullString(description),
+		Content:     nullString(content),
+	})
+}
+
+// nullString maps an empty string to SQL NULL.
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
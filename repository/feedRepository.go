@@ -4,17 +4,54 @@ import (
 	"context"
 	"github.com/google/uuid"
 	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"github.com/Rach17/Go-RSS-Aggregator/data"
 	"github.com/Rach17/Go-RSS-Aggregator/db"
 )
 
 type FeedRepository interface {
-	CreateFeed(ctx context.Context, title, url, description, language string) (db.Feed, error)
+	CreateFeed(ctx context.Context, title, url, description, language, siteURL, imageURL string, owner uuid.NullUUID, credentials []byte) (db.Feed, error)
 	GetFeedByID(ctx context.Context, id uuid.UUID) (db.Feed, error)
 	GetFeedByURL(ctx context.Context, url string) (db.Feed, error)
-	UpdateFeedLastFetchedAt(ctx context.Context, url string) error
-	GetAllFeeds(ctx context.Context) ([]db.Feed, error)
+	GetVisibleFeedByURL(ctx context.Context, url string, userID uuid.UUID) (db.Feed, error)
+	UpdateFeedLastFetchedAt(ctx context.Context, feedID uuid.UUID) error
+	UpdateFeedCacheValidators(ctx context.Context, feedID uuid.UUID, etag, lastModified string) error
+	GetAllFeeds(ctx context.Context, userID uuid.UUID) ([]db.Feed, error)
 	FollowFeed(ctx context.Context, userID uuid.UUID, feedID uuid.UUID) error
-	GetLastFetchedFeeds(ctx context.Context, limit int) ([]db.Feed, error)
+	ClaimDueFeeds(ctx context.Context, owner string, lease time.Duration, limit int) ([]db.Feed, error)
+	ReleaseFeedLease(ctx context.Context, feedID uuid.UUID, owner string) error
+	UpdateFeedMetadata(ctx context.Context, id uuid.UUID, metadata FeedMetadata, events []data.FeedEvent) error
+	GetFeedEvents(ctx context.Context, feedID uuid.UUID) ([]db.FeedEvent, error)
+	MoveFeedURL(ctx context.Context, feedID uuid.UUID, oldURL, newURL string) (db.Feed, error)
+	RecordFetchSuccess(ctx context.Context, feedID uuid.UUID, nextFetchAt time.Time, interval time.Duration, warning string) error
+	RecordFetchFailure(ctx context.Context, feedID uuid.UUID, consecutiveErrors int, lastError string, nextRetryAt time.Time, status string) error
+	UpdateFeedPollHints(ctx context.Context, feedID uuid.UUID, hints FeedPollHints) error
+	GetFeedImage(ctx context.Context, feedID uuid.UUID) (db.FeedImage, error)
+	UpsertFeedImage(ctx context.Context, feedID uuid.UUID, sourceURL, contentType string, data []byte) error
+	GetFeedCredentials(ctx context.Context, feedID uuid.UUID) ([]byte, error)
+	SetFeedCredentials(ctx context.Context, feedID uuid.UUID, encrypted []byte) error
+}
+
+// FeedMetadata holds the descriptive fields of a feed that are refreshed from
+// the feed document on every fetch.
+type FeedMetadata struct {
+	Title       string
+	Description string
+	Language    string
+	SiteURL     string
+	ImageURL    string
+}
+
+// FeedPollHints are the polling hints a feed publishes. Zero values mean the
+// feed gives no hint. SkipHours and SkipDays are comma-separated lists.
+type FeedPollHints struct {
+	TTLMinutes          int
+	UpdatePeriodMinutes int
+	SkipHours           string
+	SkipDays            string
 }
 
 type DBFeedRepository struct {
@@ -29,13 +66,38 @@ func NewDBFeedRepository(database *sql.DB) *DBFeedRepository {
 	}
 }
 
-func (r *DBFeedRepository) CreateFeed(ctx context.Context, title, url, description, language string) (db.Feed, error) {
-	return r.queries.CreateFeed(ctx, db.CreateFeedParams{
+// CreateFeed stores a new feed, private to owner when owner is set. The
+// encrypted credentials of a private feed, if any, are stored with it in the
+// same transaction.
+func (r *DBFeedRepository) CreateFeed(ctx context.Context, title, url, description, language, siteURL, imageURL string, owner uuid.NullUUID, credentials []byte) (db.Feed, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return db.Feed{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := r.queries.WithTx(tx)
+	feed, err := queries.CreateFeed(ctx, db.CreateFeedParams{
 		Title:       title,
 		Url:         url,
 		Description: sql.NullString{String: description, Valid: description != ""},
 		Language:    language,
+		SiteUrl:     sql.NullString{String: siteURL, Valid: siteURL != ""},
+		ImageUrl:    sql.NullString{String: imageURL, Valid: imageURL != ""},
+		OwnerUserID: owner,
 	})
+	if err != nil {
+		return db.Feed{}, err
+	}
+	if credentials != nil {
+		if err := queries.UpsertFeedCredentials(ctx, db.UpsertFeedCredentialsParams{
+			FeedID:    feed.ID,
+			Encrypted: credentials,
+		}); err != nil {
+			return db.Feed{}, err
+		}
+	}
+	return feed, tx.Commit()
 }
 
 func (r *DBFeedRepository) GetFeedByID(ctx context.Context, id uuid.UUID) (db.Feed, error) {
@@ -54,13 +116,30 @@ func (r *DBFeedRepository) GetFeedByURL(ctx context.Context, url string) (db.Fee
 	return feed, nil
 }
 
+// GetVisibleFeedByURL returns the feed userID sees at url: their own private
+// feed if they have one there, else the public feed.
+func (r *DBFeedRepository) GetVisibleFeedByURL(ctx context.Context, url string, userID uuid.UUID) (db.Feed, error) {
+	return r.queries.GetVisibleFeedByURL(ctx, db.GetVisibleFeedByURLParams{
+		Url:    url,
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+	})
+}
 
-func (r *DBFeedRepository) UpdateFeedLastFetchedAt(ctx context.Context, url string) error {
-	return r.queries.UpdateFeedLastFetchedAt(ctx, url)
+func (r *DBFeedRepository) UpdateFeedLastFetchedAt(ctx context.Context, feedID uuid.UUID) error {
+	return r.queries.UpdateFeedLastFetchedAt(ctx, feedID)
 }
 
-func (r *DBFeedRepository) GetAllFeeds(ctx context.Context) ([]db.Feed, error) {
-	feeds, err := r.queries.GetAllFeeds(ctx)
+func (r *DBFeedRepository) UpdateFeedCacheValidators(ctx context.Context, feedID uuid.UUID, etag, lastModified string) error {
+	return r.queries.UpdateFeedCacheValidators(ctx, db.UpdateFeedCacheValidatorsParams{
+		ID:           feedID,
+		Etag:         sql.NullString{String: etag, Valid: etag != ""},
+		LastModified: sql.NullString{String: lastModified, Valid: lastModified != ""},
+	})
+}
+
+// GetAllFeeds returns the public feeds and the private feeds of userID.
+func (r *DBFeedRepository) GetAllFeeds(ctx context.Context, userID uuid.UUID) ([]db.Feed, error) {
+	feeds, err := r.queries.GetAllFeeds(ctx, uuid.NullUUID{UUID: userID, Valid: true})
 	if err != nil {
 		return nil, err
 	}
@@ -74,10 +153,203 @@ func (r *DBFeedRepository) FollowFeed(ctx context.Context, userID uuid.UUID, fee
 	})
 }
 
-func (r *DBFeedRepository) GetLastFetchedFeeds(ctx context.Context, limit int) ([]db.Feed, error) {
-	feeds, err := r.queries.GetLastFetchedFeeds(ctx, int32(limit))
+// ClaimDueFeeds leases up to limit due feeds to owner for the given duration
+// and returns them, longest overdue first. Feeds leased to another owner are
+// skipped until their lease is released or expires.
+func (r *DBFeedRepository) ClaimDueFeeds(ctx context.Context, owner string, lease time.Duration, limit int) ([]db.Feed, error) {
+	feeds, err := r.queries.ClaimDueFeeds(ctx, db.ClaimDueFeedsParams{
+		LeaseOwner:   sql.NullString{String: owner, Valid: true},
+		LeaseSeconds: int32(lease / time.Second),
+		MaxFeeds:     int32(limit),
+	})
 	if err != nil {
 		return nil, err
 	}
+	// RETURNING does not keep the order of the subquery
+	sort.Slice(feeds, func(i, j int) bool {
+		return feeds[i].NextFetchAt.Before(feeds[j].NextFetchAt)
+	})
 	return feeds, nil
-}
\ No newline at end of file
+}
+
+// ReleaseFeedLease gives up owner's lease on a feed. A lease that has since
+// expired and been claimed by another owner is left alone.
+func (r *DBFeedRepository) ReleaseFeedLease(ctx context.Context, feedID uuid.UUID, owner string) error {
+	return r.queries.ReleaseFeedLease(ctx, db.ReleaseFeedLeaseParams{
+		ID:         feedID,
+		LeaseOwner: sql.NullString{String: owner, Valid: true},
+	})
+}
+
+// UpdateFeedMetadata stores new metadata for a feed together with the events
+// describing what changed, in a single transaction.
+func (r *DBFeedRepository) UpdateFeedMetadata(ctx context.Context, id uuid.UUID, metadata FeedMetadata, events []data.FeedEvent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := r.queries.WithTx(tx)
+	if err := queries.UpdateFeedMetadata(ctx, db.UpdateFeedMetadataParams{
+		ID:          id,
+		Title:       metadata.Title,
+		Description: sql.NullString{String: metadata.Description, Valid: metadata.Description != ""},
+		Language:    metadata.Language,
+		SiteUrl:     sql.NullString{String: metadata.SiteURL, Valid: metadata.SiteURL != ""},
+		ImageUrl:    sql.NullString{String: metadata.ImageURL, Valid: metadata.ImageURL != ""},
+	}); err != nil {
+		return err
+	}
+	for _, event := range events {
+		if err := queries.CreateFeedEvent(ctx, db.CreateFeedEventParams{
+			FeedID:    id,
+			EventType: event.Type,
+			OldValue:  sql.NullString{String: event.OldValue, Valid: event.OldValue != ""},
+			NewValue:  sql.NullString{String: event.NewValue, Valid: event.NewValue != ""},
+		}); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *DBFeedRepository) GetFeedEvents(ctx context.Context, feedID uuid.UUID) ([]db.FeedEvent, error) {
+	return r.queries.GetFeedEventsByFeedID(ctx, feedID)
+}
+
+func (r *DBFeedRepository) GetFeedImage(ctx context.Context, feedID uuid.UUID) (db.FeedImage, error) {
+	return r.queries.GetFeedImage(ctx, feedID)
+}
+
+func (r *DBFeedRepository) UpsertFeedImage(ctx context.Context, feedID uuid.UUID, sourceURL, contentType string, data []byte) error {
+	return r.queries.UpsertFeedImage(ctx, db.UpsertFeedImageParams{
+		FeedID:      feedID,
+		SourceUrl:   sourceURL,
+		ContentType: contentType,
+		Data:        data,
+	})
+}
+
+// GetFeedCredentials returns the encrypted credentials of a feed, or nil if
+// it has none.
+func (r *DBFeedRepository) GetFeedCredentials(ctx context.Context, feedID uuid.UUID) ([]byte, error) {
+	credentials, err := r.queries.GetFeedCredentials(ctx, feedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return credentials.Encrypted, nil
+}
+
+// SetFeedCredentials replaces the encrypted credentials of a feed. Nil
+// credentials remove them.
+func (r *DBFeedRepository) SetFeedCredentials(ctx context.Context, feedID uuid.UUID, encrypted []byte) error {
+	if encrypted == nil {
+		return r.queries.DeleteFeedCredentials(ctx, feedID)
+	}
+	return r.queries.UpsertFeedCredentials(ctx, db.UpsertFeedCredentialsParams{
+		FeedID:    feedID,
+		Encrypted: encrypted,
+	})
+}
+
+// MoveFeedURL moves a feed from oldURL to newURL, keeping oldURL as an alias.
+// When newURL already belongs to another feed, the followers, posts, aliases
+// and history of the moved feed are merged into that feed and the moved feed
+// is deleted. It returns the feed now found at newURL.
+func (r *DBFeedRepository) MoveFeedURL(ctx context.Context, feedID uuid.UUID, oldURL, newURL string) (db.Feed, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return db.Feed{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := r.queries.WithTx(tx)
+	targetID := feedID
+	existing, err := queries.GetFeedByURL(ctx, newURL)
+	switch {
+	case errors.Is(err, sql.ErrNoRows) || (err == nil && existing.ID == feedID):
+		// newURL may be an alias of this very feed if it is moving back
+		if err := queries.DeleteFeedAlias(ctx, newURL); err != nil {
+			return db.Feed{}, err
+		}
+		if err := queries.UpdateFeedURL(ctx, db.UpdateFeedURLParams{ID: feedID, Url: newURL}); err != nil {
+			return db.Feed{}, err
+		}
+	case err != nil:
+		return db.Feed{}, err
+	default:
+		targetID = existing.ID
+		if err := queries.MoveFeedFollows(ctx, db.MoveFeedFollowsParams{ToFeedID: targetID, FromFeedID: feedID}); err != nil {
+			return db.Feed{}, err
+		}
+		if err := queries.MoveFeedPosts(ctx, db.MoveFeedPostsParams{ToFeedID: targetID, FromFeedID: feedID}); err != nil {
+			return db.Feed{}, err
+		}
+		if err := queries.MoveFeedAliases(ctx, db.MoveFeedAliasesParams{ToFeedID: targetID, FromFeedID: feedID}); err != nil {
+			return db.Feed{}, err
+		}
+		if err := queries.MoveFeedEvents(ctx, db.MoveFeedEventsParams{ToFeedID: targetID, FromFeedID: feedID}); err != nil {
+			return db.Feed{}, err
+		}
+		if err := queries.DeleteFeed(ctx, feedID); err != nil {
+			return db.Feed{}, err
+		}
+	}
+
+	if err := queries.CreateFeedAlias(ctx, db.CreateFeedAliasParams{Url: oldURL, FeedID: targetID}); err != nil {
+		return db.Feed{}, err
+	}
+	if err := queries.CreateFeedEvent(ctx, db.CreateFeedEventParams{
+		FeedID:    targetID,
+		EventType: data.FeedEventURLMoved,
+		OldValue:  sql.NullString{String: oldURL, Valid: true},
+		NewValue:  sql.NullString{String: newURL, Valid: true},
+	}); err != nil {
+		return db.Feed{}, err
+	}
+
+	feed, err := queries.GetFeedByID(ctx, targetID)
+	if err != nil {
+		return db.Feed{}, err
+	}
+	return feed, tx.Commit()
+}
+
+// RecordFetchSuccess stores a successful fetch and schedules the next fetch
+// at nextFetchAt, interval after this one. warning describes a problem that
+// did not make the fetch fail and may be empty.
+func (r *DBFeedRepository) RecordFetchSuccess(ctx context.Context, feedID uuid.UUID, nextFetchAt time.Time, interval time.Duration, warning string) error {
+	return r.queries.RecordFeedFetchSuccess(ctx, db.RecordFeedFetchSuccessParams{
+		ID:                   feedID,
+		NextFetchAt:          nextFetchAt,
+		FetchIntervalSeconds: sql.NullInt32{Int32: int32(interval / time.Second), Valid: interval > 0},
+		FetchWarning:         sql.NullString{String: warning, Valid: warning != ""},
+	})
+}
+
+// RecordFetchFailure stores the outcome of a failed fetch and schedules the
+// next fetch at nextRetryAt. A zero nextRetryAt leaves the feed without a
+// retry time, as for disabled feeds.
+func (r *DBFeedRepository) RecordFetchFailure(ctx context.Context, feedID uuid.UUID, consecutiveErrors int, lastError string, nextRetryAt time.Time, status string) error {
+	return r.queries.RecordFeedFetchFailure(ctx, db.RecordFeedFetchFailureParams{
+		ID:                feedID,
+		ConsecutiveErrors: int32(consecutiveErrors),
+		LastError:         sql.NullString{String: lastError, Valid: lastError != ""},
+		NextRetryAt:       sql.NullTime{Time: nextRetryAt, Valid: !nextRetryAt.IsZero()},
+		FetchStatus:       status,
+	})
+}
+
+func (r *DBFeedRepository) UpdateFeedPollHints(ctx context.Context, feedID uuid.UUID, hints FeedPollHints) error {
+	return r.queries.UpdateFeedPollHints(ctx, db.UpdateFeedPollHintsParams{
+		ID:                  feedID,
+		TtlMinutes:          sql.NullInt32{Int32: int32(hints.TTLMinutes), Valid: hints.TTLMinutes > 0},
+		UpdatePeriodMinutes: sql.NullInt32{Int32: int32(hints.UpdatePeriodMinutes), Valid: hints.UpdatePeriodMinutes > 0},
+		SkipHours:           sql.NullString{String: hints.SkipHours, Valid: hints.SkipHours != ""},
+		SkipDays:            sql.NullString{String: hints.SkipDays, Valid: hints.SkipDays != ""},
+	})
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+    "context"
     "database/sql"
+    "fmt"
     "log"
     "os"
     "os/signal"
@@ -11,6 +13,7 @@ import (
 
     "github.com/Rach17/Go-RSS-Aggregator/repository"
     "github.com/Rach17/Go-RSS-Aggregator/service"
+    "github.com/Rach17/Go-RSS-Aggregator/utils"
     "github.com/joho/godotenv"
     _ "github.com/lib/pq"
 )
@@ -47,8 +50,33 @@ func main() {
     feedRepo := repository.NewDBFeedRepository(connection)
     feedPostRepo := repository.NewDBFeedPostRepository(connection)
     feedService := service.NewFeedService(feedRepo, feedPostRepo)
+    feedService.FetchInterval = config.Interval
+    feedService.MinFetchInterval = config.MinInterval
+    feedService.MaxFetchInterval = config.MaxInterval
+    feedService.MaxConsecutiveErrors = config.MaxFeedErrors
+    feedService.RetryBackoff = config.RetryBackoff
     scraperService := service.NewScraperService(feedService, feedRepo, config.FeedsToFetch)
+    if config.InstanceID != "" {
+        scraperService.InstanceID = config.InstanceID
+    }
+    scraperService.LeaseDuration = config.LeaseDuration
+    feedService.HostLimiter.MaxConcurrent = config.PerHostLimit
+    feedService.HostLimiter.MinDelay = config.PerHostDelay
+    feedService.RobotsTTL = config.RobotsTTL
+    feedService.NetworkPolicy = config.NetworkPolicy
+    feedService.MaxFeedBytes = config.MaxFeedBytes
+    feedService.MaxFeedItems = config.MaxFeedItems
+    feedService.CredentialsKey = config.CredentialsKey
 
+    // Re-apply the current HTML sanitising policy to stored posts if requested
+    if config.Resanitize {
+        feedPostService := service.NewFeedPostService(feedRepo, feedPostRepo)
+        count, err := feedPostService.ResanitizeFeedPosts(context.Background())
+        if err != nil {
+            log.Fatalf("Failed to re-sanitise feed posts: %v", err)
+        }
+        log.Printf("Re-sanitised %d feed posts", count)
+    }
 
     // Setup graceful shutdown
     sigChan := make(chan os.Signal, 1)
@@ -56,7 +84,7 @@ func main() {
 
     // Start scraper
     log.Printf("Starting RSS scraper with config: %+v", config)
-    scraperService.Start(config.Interval)
+    scraperService.Start(config.Tick)
 
     // Run initial scrape if configured
     if config.InitialScrape {
@@ -77,16 +105,55 @@ func main() {
 }
 
 type ScraperConfig struct {
-    Interval       time.Duration
+    Interval       time.Duration // how often a feed is fetched until its posting frequency is known
+    MinInterval    time.Duration // bounds of the adaptive per-feed interval
+    MaxInterval    time.Duration
+    Tick           time.Duration // how often the scraper looks for due feeds
     FeedsToFetch   int
     InitialScrape  bool
+    Resanitize     bool
+    MaxFeedErrors  int
+    RetryBackoff   time.Duration
+    InstanceID     string        // identifies this scraper in feed leases; generated when empty
+    LeaseDuration  time.Duration // how long a claimed feed is reserved for this scraper
+    PerHostLimit   int           // requests in flight per host (0 for no limit)
+    PerHostDelay   time.Duration // minimum time between two requests to a host
+    RobotsTTL      time.Duration // how long a site's robots.txt is cached
+    NetworkPolicy  *service.NetworkPolicy
+    MaxFeedBytes   int64         // feed bodies are cut at this size (0 for no limit)
+    MaxFeedItems   int           // items kept per feed document (0 for no limit)
+    CredentialsKey secretKey     // decrypts the credentials of private feeds
+}
+
+// secretKey is key material that never shows up when printed, so the config
+// can be logged as a whole.
+type secretKey []byte
+
+func (k secretKey) Format(f fmt.State, verb rune) {
+    if len(k) == 0 {
+        fmt.Fprint(f, "<none>")
+        return
+    }
+    fmt.Fprint(f, "<redacted>")
 }
 
 func getScraperConfig() ScraperConfig {
     config := ScraperConfig{
         Interval:      60 * time.Minute, // Default: 1 hour
-        FeedsToFetch:  10,               // Default: 10 feeds per cycle
+        MinInterval:   5 * time.Minute,  // Default: poll a feed at most every 5 minutes
+        MaxInterval:   24 * time.Hour,   // Default: poll a feed at least once a day
+        Tick:          time.Minute,      // Default: look for due feeds every minute
+        FeedsToFetch:  10,               // Default: 10 feeds per batch
         InitialScrape: true,             // Default: run initial scrape
+        MaxFeedErrors: 10,               // Default: disable a feed after 10 failures in a row
+        RetryBackoff:  15 * time.Minute, // Default: first retry 15 minutes after a failure
+        InstanceID:    os.Getenv("SCRAPER_INSTANCE_ID"),
+        LeaseDuration: 5 * time.Minute,  // Default: a crashed scraper's feeds are picked up after 5 minutes
+        PerHostLimit:  2,                // Default: at most 2 requests in flight per host
+        PerHostDelay:  time.Second,      // Default: start at most one request per second per host
+        RobotsTTL:     24 * time.Hour,   // Default: re-read robots.txt daily
+        MaxFeedBytes:  10 << 20,         // Default: read at most 10 MiB of a feed
+        MaxFeedItems:  500,              // Default: keep at most 500 items per document
     }
 
     // Get scraper interval (in minutes)
@@ -98,7 +165,36 @@ func getScraperConfig() ScraperConfig {
         }
     }
 
-    // Get number of feeds to fetch per cycle
+    // Get the bounds of the adaptive per-feed interval (in minutes)
+    if minStr := os.Getenv("SCRAPER_MIN_INTERVAL_MINUTES"); minStr != "" {
+        if minMinutes, err := strconv.Atoi(minStr); err == nil && minMinutes > 0 {
+            config.MinInterval = time.Duration(minMinutes) * time.Minute
+        } else {
+            log.Printf("Invalid SCRAPER_MIN_INTERVAL_MINUTES: %v, using default", err)
+        }
+    }
+    if maxStr := os.Getenv("SCRAPER_MAX_INTERVAL_MINUTES"); maxStr != "" {
+        if maxMinutes, err := strconv.Atoi(maxStr); err == nil && maxMinutes > 0 {
+            config.MaxInterval = time.Duration(maxMinutes) * time.Minute
+        } else {
+            log.Printf("Invalid SCRAPER_MAX_INTERVAL_MINUTES: %v, using default", err)
+        }
+    }
+    if config.MaxInterval < config.MinInterval {
+        log.Printf("SCRAPER_MAX_INTERVAL_MINUTES is below SCRAPER_MIN_INTERVAL_MINUTES, using the minimum for both")
+        config.MaxInterval = config.MinInterval
+    }
+
+    // Get how often to look for due feeds (in seconds)
+    if tickStr := os.Getenv("SCRAPER_TICK_SECONDS"); tickStr != "" {
+        if tickSeconds, err := strconv.Atoi(tickStr); err == nil && tickSeconds > 0 {
+            config.Tick = time.Duration(tickSeconds) * time.Second
+        } else {
+            log.Printf("Invalid SCRAPER_TICK_SECONDS: %v, using default", err)
+        }
+    }
+
+    // Get number of feeds to fetch per batch
     if feedsStr := os.Getenv("SCRAPER_FEEDS_TO_FETCH"); feedsStr != "" {
         if feeds, err := strconv.Atoi(feedsStr); err == nil && feeds > 0 {
             config.FeedsToFetch = int(feeds)
@@ -116,5 +212,101 @@ func getScraperConfig() ScraperConfig {
         }
     }
 
+    // Get re-sanitise setting
+    if resanitizeStr := os.Getenv("SCRAPER_RESANITIZE"); resanitizeStr != "" {
+        if resanitize, err := strconv.ParseBool(resanitizeStr); err == nil {
+            config.Resanitize = resanitize
+        } else {
+            log.Printf("Invalid SCRAPER_RESANITIZE: %v, using default", err)
+        }
+    }
+
+    // Get number of consecutive errors after which a feed is disabled (0 never disables)
+    if maxErrorsStr := os.Getenv("SCRAPER_MAX_FEED_ERRORS"); maxErrorsStr != "" {
+        if maxErrors, err := strconv.Atoi(maxErrorsStr); err == nil && maxErrors >= 0 {
+            config.MaxFeedErrors = maxErrors
+        } else {
+            log.Printf("Invalid SCRAPER_MAX_FEED_ERRORS: %v, using default", err)
+        }
+    }
+
+    // Get the initial retry backoff after a failed fetch (in minutes)
+    if backoffStr := os.Getenv("SCRAPER_RETRY_BACKOFF_MINUTES"); backoffStr != "" {
+        if backoffMinutes, err := strconv.Atoi(backoffStr); err == nil && backoffMinutes > 0 {
+            config.RetryBackoff = time.Duration(backoffMinutes) * time.Minute
+        } else {
+            log.Printf("Invalid SCRAPER_RETRY_BACKOFF_MINUTES: %v, using default", err)
+        }
+    }
+
+    // Get how long a claimed feed stays reserved for this scraper (in seconds)
+    if leaseStr := os.Getenv("SCRAPER_LEASE_SECONDS"); leaseStr != "" {
+        if leaseSeconds, err := strconv.Atoi(leaseStr); err == nil && leaseSeconds > 0 {
+            config.LeaseDuration = time.Duration(leaseSeconds) * time.Second
+        } else {
+            log.Printf("Invalid SCRAPER_LEASE_SECONDS: %v, using default", err)
+        }
+    }
+
+    // Get the per-host politeness limits
+    if concurrencyStr := os.Getenv("SCRAPER_HOST_CONCURRENCY"); concurrencyStr != "" {
+        if concurrency, err := strconv.Atoi(concurrencyStr); err == nil && concurrency >= 0 {
+            config.PerHostLimit = concurrency
+        } else {
+            log.Printf("Invalid SCRAPER_HOST_CONCURRENCY: %v, using default", err)
+        }
+    }
+    if delayStr := os.Getenv("SCRAPER_HOST_DELAY_MS"); delayStr != "" {
+        if delayMs, err := strconv.Atoi(delayStr); err == nil && delayMs >= 0 {
+            config.PerHostDelay = time.Duration(delayMs) * time.Millisecond
+        } else {
+            log.Printf("Invalid SCRAPER_HOST_DELAY_MS: %v, using default", err)
+        }
+    }
+
+    // Get how long a site's robots.txt is cached (in minutes)
+    if robotsStr := os.Getenv("SCRAPER_ROBOTS_TTL_MINUTES"); robotsStr != "" {
+        if robotsMinutes, err := strconv.Atoi(robotsStr); err == nil && robotsMinutes > 0 {
+            config.RobotsTTL = time.Duration(robotsMinutes) * time.Minute
+        } else {
+            log.Printf("Invalid SCRAPER_ROBOTS_TTL_MINUTES: %v, using default", err)
+        }
+    }
+
+    // Get the size limits for feed documents
+    if maxBytesStr := os.Getenv("SCRAPER_MAX_FEED_BYTES"); maxBytesStr != "" {
+        if maxBytes, err := strconv.ParseInt(maxBytesStr, 10, 64); err == nil && maxBytes >= 0 {
+            config.MaxFeedBytes = maxBytes
+        } else {
+            log.Printf("Invalid SCRAPER_MAX_FEED_BYTES: %v, using default", err)
+        }
+    }
+    if maxItemsStr := os.Getenv("SCRAPER_MAX_FEED_ITEMS"); maxItemsStr != "" {
+        if maxItems, err := strconv.Atoi(maxItemsStr); err == nil && maxItems >= 0 {
+            config.MaxFeedItems = maxItems
+        } else {
+            log.Printf("Invalid SCRAPER_MAX_FEED_ITEMS: %v, using default", err)
+        }
+    }
+
+    // Get the hosts and ports feeds may be fetched from (comma-separated lists)
+    networkPolicy, err := service.NewNetworkPolicy(
+        os.Getenv("FETCH_ALLOW_HOSTS"), os.Getenv("FETCH_DENY_HOSTS"),
+        os.Getenv("FETCH_ALLOW_PORTS"), os.Getenv("FETCH_DENY_PORTS"),
+    )
+    if err != nil {
+        log.Fatalf("Invalid fetch network policy: %v", err)
+    }
+    config.NetworkPolicy = networkPolicy
+
+    // Get the key the credentials of private feeds are encrypted with (base64)
+    if keyStr := os.Getenv("FEED_CREDENTIALS_KEY"); keyStr != "" {
+        key, err := utils.ParseEncryptionKey(keyStr)
+        if err != nil {
+            log.Fatalf("Invalid FEED_CREDENTIALS_KEY: %v", err)
+        }
+        config.CredentialsKey = key
+    }
+
     return config
 }
\ No newline at end of file
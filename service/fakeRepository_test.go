@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Rach17/Go-RSS-Aggregator/data"
+	"github.com/Rach17/Go-RSS-Aggregator/db"
+	"github.com/Rach17/Go-RSS-Aggregator/repository"
+	"github.com/google/uuid"
+)
+
+// fakeFeedRepo keeps feeds in memory. Methods the tests do not need are left
+// to the embedded nil interface and panic if called.
+type fakeFeedRepo struct {
+	repository.FeedRepository
+
+	mu          sync.Mutex
+	feeds       map[uuid.UUID]db.Feed
+	images      map[uuid.UUID]db.FeedImage
+	credentials map[uuid.UUID][]byte
+	aliases     map[string]uuid.UUID
+}
+
+func newFakeFeedRepo() *fakeFeedRepo {
+	return &fakeFeedRepo{
+		feeds:       make(map[uuid.UUID]db.Feed),
+		images:      make(map[uuid.UUID]db.FeedImage),
+		credentials: make(map[uuid.UUID][]byte),
+		aliases:     make(map[string]uuid.UUID),
+	}
+}
+
+func (r *fakeFeedRepo) CreateFeed(ctx context.Context, title, url, description, language, siteURL, imageURL string, owner uuid.NullUUID, credentials []byte) (db.Feed, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	feed := db.Feed{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		Title:       title,
+		Url:         url,
+		Description: sql.NullString{String: description, Valid: description != ""},
+		Language:    language,
+		SiteUrl:     sql.NullString{String: siteURL, Valid: siteURL != ""},
+		ImageUrl:    sql.NullString{String: imageURL, Valid: imageURL != ""},
+		FetchStatus: data.FetchStatusOK,
+		OwnerUserID: owner,
+	}
+	r.feeds[feed.ID] = feed
+	if credentials != nil {
+		r.credentials[feed.ID] = credentials
+	}
+	return feed, nil
+}
+
+func (r *fakeFeedRepo) GetFeedByID(ctx context.Context, id uuid.UUID) (db.Feed, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	feed, ok := r.feeds[id]
+	if !ok {
+		return db.Feed{}, sql.ErrNoRows
+	}
+	return feed, nil
+}
+
+func (r *fakeFeedRepo) GetFeedByURL(ctx context.Context, url string) (db.Feed, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, feed := range r.feeds {
+		if feed.Url == url && !feed.OwnerUserID.Valid {
+			return feed, nil
+		}
+	}
+	if feed, ok := r.feeds[r.aliases[url]]; ok && !feed.OwnerUserID.Valid {
+		return feed, nil
+	}
+	return db.Feed{}, sql.ErrNoRows
+}
+
+func (r *fakeFeedRepo) GetVisibleFeedByURL(ctx context.Context, url string, userID uuid.UUID) (db.Feed, error) {
+	r.mu.Lock()
+	for _, feed := range r.feeds {
+		if feed.Url == url && feed.OwnerUserID.Valid && feed.OwnerUserID.UUID == userID {
+			r.mu.Unlock()
+			return feed, nil
+		}
+	}
+	r.mu.Unlock()
+	return r.GetFeedByURL(ctx, url)
+}
+
+func (r *fakeFeedRepo) UpdateFeedLastFetchedAt(ctx context.Context, feedID uuid.UUID) error {
+	return nil
+}
+
+func (r *fakeFeedRepo) UpdateFeedCacheValidators(ctx context.Context, feedID uuid.UUID, etag, lastModified string) error {
+	return nil
+}
+
+func (r *fakeFeedRepo) UpdateFeedMetadata(ctx context.Context, id uuid.UUID, metadata repository.FeedMetadata, events []data.FeedEvent) error {
+	return nil
+}
+
+func (r *fakeFeedRepo) ClaimDueFeeds(ctx context.Context, owner string, lease time.Duration, limit int) ([]db.Feed, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	var due []db.Feed
+	for _, feed := range r.feeds {
+		if feed.FetchStatus == data.FetchStatusDisabled || feed.NextFetchAt.After(now) {
+			continue
+		}
+		if feed.LeaseExpiresAt.Valid && feed.LeaseExpiresAt.Time.After(now) {
+			continue
+		}
+		due = append(due, feed)
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextFetchAt.Before(due[j].NextFetchAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for i := range due {
+		due[i].LeaseOwner = sql.NullString{String: owner, Valid: true}
+		due[i].LeaseExpiresAt = sql.NullTime{Time: now.Add(lease), Valid: true}
+		r.feeds[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (r *fakeFeedRepo) ReleaseFeedLease(ctx context.Context, feedID uuid.UUID, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	feed := r.feeds[feedID]
+	if feed.LeaseOwner.String == owner {
+		feed.LeaseOwner = sql.NullString{}
+		feed.LeaseExpiresAt = sql.NullTime{}
+		r.feeds[feedID] = feed
+	}
+	return nil
+}
+
+func (r *fakeFeedRepo) UpdateFeedPollHints(ctx context.Context, feedID uuid.UUID, hints repository.FeedPollHints) error {
+	return nil
+}
+
+func (r *fakeFeedRepo) MoveFeedURL(ctx context.Context, feedID uuid.UUID, oldURL, newURL string) (db.Feed, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	target := r.feeds[feedID]
+	for _, other := range r.feeds {
+		if other.Url == newURL && !other.OwnerUserID.Valid && other.ID != feedID {
+			target = other
+		}
+	}
+	if target.ID == feedID {
+		delete(r.aliases, newURL)
+		target.Url = newURL
+		r.feeds[feedID] = target
+	} else {
+		for url, id := range r.aliases {
+			if id == feedID {
+				r.aliases[url] = target.ID
+			}
+		}
+		delete(r.feeds, feedID)
+	}
+	r.aliases[oldURL] = target.ID
+	return target, nil
+}
+
+func (r *fakeFeedRepo) RecordFetchSuccess(ctx context.Context, feedID uuid.UUID, nextFetchAt time.Time, interval time.Duration, warning string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	feed := r.feeds[feedID]
+	feed.ConsecutiveErrors = 0
+	feed.LastError = sql.NullString{}
+	feed.LastSuccessAt = sql.NullTime{Time: time.Now(), Valid: true}
+	feed.NextRetryAt = sql.NullTime{}
+	feed.FetchStatus = data.FetchStatusOK
+	feed.NextFetchAt = nextFetchAt
+	feed.FetchIntervalSeconds = sql.NullInt32{Int32: int32(interval / time.Second), Valid: interval > 0}
+	feed.FetchWarning = sql.NullString{String: warning, Valid: warning != ""}
+	r.feeds[feedID] = feed
+	return nil
+}
+
+func (r *fakeFeedRepo) RecordFetchFailure(ctx context.Context, feedID uuid.UUID, consecutiveErrors int, lastError string, nextRetryAt time.Time, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	feed := r.feeds[feedID]
+	feed.ConsecutiveErrors = int32(consecutiveErrors)
+	feed.LastError = sql.NullString{String: lastError, Valid: lastError != ""}
+	feed.NextRetryAt = sql.NullTime{Time: nextRetryAt, Valid: !nextRetryAt.IsZero()}
+	if !nextRetryAt.IsZero() {
+		feed.NextFetchAt = nextRetryAt
+	}
+	feed.FetchStatus = status
+	r.feeds[feedID] = feed
+	return nil
+}
+
+func (r *fakeFeedRepo) GetFeedImage(ctx context.Context, feedID uuid.UUID) (db.FeedImage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	image, ok := r.images[feedID]
+	if !ok {
+		return db.FeedImage{}, sql.ErrNoRows
+	}
+	return image, nil
+}
+
+func (r *fakeFeedRepo) UpsertFeedImage(ctx context.Context, feedID uuid.UUID, sourceURL, contentType string, data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.images[feedID] = db.FeedImage{FeedID: feedID, CreatedAt: time.Now(), SourceUrl: sourceURL, ContentType: contentType, Data: data}
+	return nil
+}
+
+func (r *fakeFeedRepo) GetFeedCredentials(ctx context.Context, feedID uuid.UUID) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.credentials[feedID], nil
+}
+
+// fakePostRepo keeps posts in memory.
+type fakePostRepo struct {
+	repository.FeedPostRepository
+
+	mu        sync.Mutex
+	posts     []repository.CreateFeedPostInput
+	postTimes []time.Time
+}
+
+func (r *fakePostRepo) Create(ctx context.Context, post repository.CreateFeedPostInput) (uuid.UUID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.posts = append(r.posts, post)
+	return uuid.New(), nil
+}
+
+func (r *fakePostRepo) CreateEnclosure(ctx context.Context, postID uuid.UUID, url, mimeType string, length int64) error {
+	return nil
+}
+
+func (r *fakePostRepo) AddTag(ctx context.Context, postID uuid.UUID, name string) error {
+	return nil
+}
+
+func (r *fakePostRepo) GetFeedPostGuids(ctx context.Context, feedID uuid.UUID) (map[string]bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	guids := make(map[string]bool)
+	for _, post := range r.posts {
+		if post.FeedID == feedID {
+			guids[post.Guid] = true
+		}
+	}
+	return guids, nil
+}
+
+func (r *fakePostRepo) GetRecentPostTimes(ctx context.Context, feedID uuid.UUID, limit int) ([]time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.postTimes) > limit {
+		return r.postTimes[:limit], nil
+	}
+	return r.postTimes, nil
+}
+
+// newTestFeedService returns a FeedService backed by the fakes that may
+// connect to the loopback test servers.
+func newTestFeedService() (*FeedService, *fakeFeedRepo, *fakePostRepo) {
+	feedRepo, postRepo := newFakeFeedRepo(), &fakePostRepo{}
+	fs := NewFeedService(feedRepo, postRepo)
+	fs.NetworkPolicy, _ = NewNetworkPolicy("127.0.0.1", "", "", "")
+	fs.HostLimiter.MinDelay = 0
+	return fs, feedRepo, postRepo
+}
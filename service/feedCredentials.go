@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Rach17/Go-RSS-Aggregator/data"
+	"github.com/Rach17/Go-RSS-Aggregator/db"
+	"github.com/Rach17/Go-RSS-Aggregator/utils"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidFeedCredentials is returned when the credentials or headers given
+// for a feed cannot be used.
+var ErrInvalidFeedCredentials = errors.New("invalid feed credentials")
+
+// ErrCredentialsKeyMissing is returned when a feed's credentials have to be
+// stored or read but no CredentialsKey is configured.
+var ErrCredentialsKeyMissing = errors.New("no key configured for feed credentials")
+
+// ErrNotFeedOwner is returned when a user changes a feed that is not their
+// own private feed.
+var ErrNotFeedOwner = errors.New("feed is not a private feed of this user")
+
+// maxRedirects matches the limit of the HTTP client's default redirect policy.
+const maxRedirects = 10
+
+type feedCredentialsContextKey struct{}
+
+// scopedCredentials are a feed's credentials together with the origin they
+// were given for. They are never sent anywhere else.
+type scopedCredentials struct {
+	credentials data.FeedCredentials
+	origin      *url.URL
+}
+
+// withFeedCredentials returns a context in which requests to the origin of
+// feedURL are sent with credentials. Requests to any other origin, such as a
+// feed discovered on another host, go without them.
+func withFeedCredentials(ctx context.Context, credentials data.FeedCredentials, feedURL string) context.Context {
+	origin, err := url.Parse(feedURL)
+	if credentials.Empty() || err != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, feedCredentialsContextKey{}, scopedCredentials{credentials: credentials, origin: origin})
+}
+
+// feedCredentialsFor returns the credentials attached to ctx if target is on
+// the origin they were given for.
+func feedCredentialsFor(ctx context.Context, target *url.URL) (data.FeedCredentials, bool) {
+	scoped, ok := ctx.Value(feedCredentialsContextKey{}).(scopedCredentials)
+	if !ok || !sameOrigin(target, scoped.origin) {
+		return data.FeedCredentials{}, false
+	}
+	return scoped.credentials, true
+}
+
+// sameOrigin reports whether a and b have the same scheme and host.
+func sameOrigin(a, b *url.URL) bool {
+	return a.Scheme == b.Scheme && strings.EqualFold(a.Host, b.Host)
+}
+
+// checkRedirect is the redirect policy of the fetcher. A redirect to another
+// origin has to be allowed by that origin's robots.txt, like the first URL.
+// The HTTP client copies custom headers to every redirect, so a feed's
+// credentials and headers are dropped as soon as a redirect leaves the origin
+// they were given for.
+func (fs *FeedService) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if !isRobotsRequest(req.Context()) && !sameOrigin(req.URL, via[len(via)-1].URL) {
+		if err := fs.checkRobots(req.Context(), req.URL); err != nil {
+			return err
+		}
+	}
+
+	scoped, ok := req.Context().Value(feedCredentialsContextKey{}).(scopedCredentials)
+	if ok && !sameOrigin(req.URL, scoped.origin) {
+		req.Header.Del("Authorization")
+		for name := range scoped.credentials.Headers {
+			req.Header.Del(name)
+		}
+	}
+	return nil
+}
+
+// encryptCredentials seals credentials for a private feed of owner. The
+// owner is bound to the sealed value, so it cannot be reused for a feed of
+// another user.
+func (fs *FeedService) encryptCredentials(credentials data.FeedCredentials, owner uuid.UUID) ([]byte, error) {
+	if credentials.Empty() {
+		return nil, nil
+	}
+	if len(fs.CredentialsKey) == 0 {
+		return nil, ErrCredentialsKeyMissing
+	}
+	plaintext, err := json.Marshal(credentials)
+	if err != nil {
+		return nil, err
+	}
+	return utils.Encrypt(fs.CredentialsKey, plaintext, owner[:])
+}
+
+// loadFeedCredentials returns the decrypted credentials of a feed. Public
+// feeds and private feeds without credentials have none.
+func (fs *FeedService) loadFeedCredentials(ctx context.Context, feed db.Feed) (data.FeedCredentials, error) {
+	if !feed.OwnerUserID.Valid {
+		return data.FeedCredentials{}, nil
+	}
+	sealed, err := fs.FeedRepo.GetFeedCredentials(ctx, feed.ID)
+	if err != nil || sealed == nil {
+		return data.FeedCredentials{}, err
+	}
+	if len(fs.CredentialsKey) == 0 {
+		return data.FeedCredentials{}, ErrCredentialsKeyMissing
+	}
+	plaintext, err := utils.Decrypt(fs.CredentialsKey, sealed, feed.OwnerUserID.UUID[:])
+	if err != nil {
+		return data.FeedCredentials{}, fmt.Errorf("failed to decrypt credentials: %w", err)
+	}
+	var credentials data.FeedCredentials
+	if err := json.Unmarshal(plaintext, &credentials); err != nil {
+		return data.FeedCredentials{}, fmt.Errorf("failed to decode credentials: %w", err)
+	}
+	return credentials, nil
+}
+
+// SetFeedCredentials replaces the credentials and headers of a private feed
+// of userID. Empty credentials remove them.
+func (fs *FeedService) SetFeedCredentials(ctx context.Context, feedURL string, userID uuid.UUID, credentials data.FeedCredentials) error {
+	if err := credentials.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFeedCredentials, err)
+	}
+	feed, err := fs.FeedRepo.GetVisibleFeedByURL(ctx, feedURL, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get feed by URL: %w", err)
+	}
+	if !feed.OwnerUserID.Valid || feed.OwnerUserID.UUID != userID {
+		return ErrNotFeedOwner
+	}
+	sealed, err := fs.encryptCredentials(credentials, userID)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt credentials: %w", err)
+	}
+	if err := fs.FeedRepo.SetFeedCredentials(ctx, feed.ID, sealed); err != nil {
+		return fmt.Errorf("failed to store credentials: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Rach17/Go-RSS-Aggregator/data"
+	"github.com/Rach17/Go-RSS-Aggregator/db"
+	"github.com/google/uuid"
+)
+
+func TestFeedCredentialsRoundTrip(t *testing.T) {
+	fs, feedRepo, _ := newTestFeedService()
+	fs.CredentialsKey = bytes.Repeat([]byte{9}, 32)
+	ctx := context.Background()
+	owner, other := uuid.New(), uuid.New()
+	credentials := data.FeedCredentials{
+		Username: "alice",
+		Password: "secret",
+		Headers:  map[string]string{"X-Api-Key": "k"},
+	}
+
+	sealed, err := fs.encryptCredentials(credentials, owner)
+	if err != nil {
+		t.Fatalf("encryptCredentials: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("secret")) {
+		t.Fatal("sealed credentials contain the password")
+	}
+	ownFeed, _ := feedRepo.CreateFeed(ctx, "Own", "https://example.com/own", "", "", "", "", uuid.NullUUID{UUID: owner, Valid: true}, sealed)
+	// The same sealed value copied to a feed of another user
+	otherFeed, _ := feedRepo.CreateFeed(ctx, "Other", "https://example.com/own", "", "", "", "", uuid.NullUUID{UUID: other, Valid: true}, sealed)
+	publicFeed, _ := feedRepo.CreateFeed(ctx, "Public", "https://example.com/public", "", "", "", "", uuid.NullUUID{}, nil)
+
+	key := fs.CredentialsKey
+	tests := []struct {
+		name    string
+		feed    db.Feed
+		key     []byte
+		want    data.FeedCredentials
+		wantErr bool
+	}{
+		{"owner", ownFeed, key, credentials, false},
+		{"bound to another owner", otherFeed, key, data.FeedCredentials{}, true},
+		{"other key", ownFeed, bytes.Repeat([]byte{8}, 32), data.FeedCredentials{}, true},
+		{"no key", ownFeed, nil, data.FeedCredentials{}, true},
+		{"public feed", publicFeed, key, data.FeedCredentials{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs.CredentialsKey = tt.key
+			got, err := fs.loadFeedCredentials(ctx, tt.feed)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadFeedCredentials error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.key == nil && !errors.Is(err, ErrCredentialsKeyMissing) {
+				t.Errorf("got %v, want ErrCredentialsKeyMissing", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptCredentialsWithoutKey(t *testing.T) {
+	fs, _, _ := newTestFeedService()
+	if sealed, err := fs.encryptCredentials(data.FeedCredentials{}, uuid.New()); err != nil || sealed != nil {
+		t.Errorf("empty credentials: got %v, %v, want nothing stored", sealed, err)
+	}
+	if _, err := fs.encryptCredentials(data.FeedCredentials{BearerToken: "t"}, uuid.New()); !errors.Is(err, ErrCredentialsKeyMissing) {
+		t.Errorf("got %v, want ErrCredentialsKeyMissing", err)
+	}
+}
+
+func TestCheckRedirectDropsCredentialsAcrossOrigins(t *testing.T) {
+	fs, _, _ := newTestFeedService()
+	// Let every redirect pass the robots.txt check without a request
+	for _, site := range []string{"http://feeds.example", "https://feeds.example", "https://cdn.example"} {
+		fs.cacheRobots(site, robotsEntry{expiresAt: time.Now().Add(time.Hour)})
+	}
+	credentials := data.FeedCredentials{BearerToken: "token", Headers: map[string]string{"X-Api-Key": "k"}}
+	ctx := withFeedCredentials(context.Background(), credentials, "https://feeds.example/feed")
+
+	tests := []struct {
+		target string
+		kept   bool
+	}{
+		{"https://feeds.example/moved", true},
+		{"https://FEEDS.example/moved", true},
+		{"http://feeds.example/moved", false},
+		{"https://cdn.example/feed", false},
+	}
+	for _, tt := range tests {
+		origin, _ := http.NewRequestWithContext(ctx, "GET", "https://feeds.example/feed", nil)
+		req, _ := http.NewRequestWithContext(ctx, "GET", tt.target, nil)
+		credentials.Apply(req)
+
+		if err := fs.checkRedirect(req, []*http.Request{origin}); err != nil {
+			t.Fatalf("checkRedirect(%s): %v", tt.target, err)
+		}
+		if got := req.Header.Get("Authorization") != ""; got != tt.kept {
+			t.Errorf("redirect to %s kept Authorization = %v, want %v", tt.target, got, tt.kept)
+		}
+		if got := req.Header.Get("X-Api-Key") != ""; got != tt.kept {
+			t.Errorf("redirect to %s kept X-Api-Key = %v, want %v", tt.target, got, tt.kept)
+		}
+	}
+}
+
+// credentialsRecorder is a server that records every request carrying a
+// feed's credentials or custom header.
+type credentialsRecorder struct {
+	mu   sync.Mutex
+	seen []string
+}
+
+func (c *credentialsRecorder) record(r *http.Request) bool {
+	if r.Header.Get("Authorization") == "" && r.Header.Get("X-Api-Key") == "" {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen = append(c.seen, r.URL.Path)
+	return true
+}
+
+func TestCredentialsNotSentToDiscoveredFeedOnAnotherHost(t *testing.T) {
+	var thirdParty credentialsRecorder
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		thirdParty.record(r)
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<rss version="2.0"><channel><title>Elsewhere</title><item><guid>1</guid></item></channel></rss>`))
+	}))
+	defer other.Close()
+
+	private := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><link rel="alternate" type="application/rss+xml" href="` + other.URL + `/feed"></head></html>`))
+	}))
+	defer private.Close()
+
+	fs, feedRepo, _ := newTestFeedService()
+	fs.CredentialsKey = bytes.Repeat([]byte{9}, 32)
+	owner := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	credentials := data.FeedCredentials{BearerToken: "token", Headers: map[string]string{"X-Api-Key": "k"}}
+
+	feed, err := fs.CreateFeed(context.Background(), private.URL+"/page", owner, credentials)
+	if err != nil {
+		t.Fatalf("CreateFeed: %v", err)
+	}
+	if feed.Url != other.URL+"/feed" {
+		t.Errorf("feed URL = %s, want the discovered feed", feed.Url)
+	}
+	if len(thirdParty.seen) > 0 {
+		t.Errorf("credentials sent to another host for %v", thirdParty.seen)
+	}
+	if sealed := feedRepo.credentials[feed.ID]; sealed != nil {
+		t.Error("credentials stored for a feed on another host")
+	}
+}
+
+func TestPrivateFeedImageFetchedWithCredentials(t *testing.T) {
+	var thirdParty credentialsRecorder
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		thirdParty.record(r)
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG\r\n\x1a\n"))
+	}))
+	defer cdn.Close()
+
+	var imageURL string
+	private := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/feed":
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Write([]byte(`<rss version="2.0"><channel><title>Private</title>
+<image><url>` + imageURL + `</url></image><item><guid>1</guid></item></channel></rss>`))
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG\r\n\x1a\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer private.Close()
+
+	tests := []struct {
+		name     string
+		imageURL string
+	}{
+		{"same origin", private.URL + "/logo.png"},
+		{"other origin", cdn.URL + "/logo.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imageURL = tt.imageURL
+			fs, feedRepo, _ := newTestFeedService()
+			fs.CredentialsKey = bytes.Repeat([]byte{9}, 32)
+			owner := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+			credentials := data.FeedCredentials{BearerToken: "token", Headers: map[string]string{"X-Api-Key": "k"}}
+
+			feed, err := fs.CreateFeed(context.Background(), private.URL+"/feed", owner, credentials)
+			if err != nil {
+				t.Fatalf("CreateFeed: %v", err)
+			}
+			image, err := feedRepo.GetFeedImage(context.Background(), feed.ID)
+			if err != nil {
+				t.Fatalf("image not cached: %v", err)
+			}
+			if image.SourceUrl != tt.imageURL {
+				t.Errorf("cached %s, want %s", image.SourceUrl, tt.imageURL)
+			}
+			if len(thirdParty.seen) > 0 {
+				t.Errorf("credentials sent to another host for %v", thirdParty.seen)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net/url"
+	"strings"
+
+	"github.com/Rach17/Go-RSS-Aggregator/data"
+	"github.com/Rach17/Go-RSS-Aggregator/utils"
+)
+
+// feedLinkTypes are the <link rel="alternate"> types that point at a feed.
+// Plain application/json is left out: CMSs such as WordPress advertise their
+// REST API with it on every page.
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/rdf+xml":   true,
+	"application/feed+json": true,
+}
+
+// commonFeedPaths are probed, in order, when a page does not advertise a feed.
+var commonFeedPaths = []string{
+	"/feed",
+	"/rss.xml",
+	"/atom.xml",
+	"/feed.xml",
+	"/index.xml",
+	"/rss",
+	"/feed.json",
+}
+
+// FeedCandidatesError is returned when a web page advertises more than one
+// feed, so the caller can let the user pick one.
+type FeedCandidatesError struct {
+	Candidates []data.FeedCandidate
+}
+
+func (e *FeedCandidatesError) Error() string {
+	return fmt.Sprintf("found %d feeds on this page, please choose one", len(e.Candidates))
+}
+
+func isHTMLDocument(contentType string, raw []byte) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+			return true
+		}
+	}
+	head := bytes.ToLower(bytes.TrimSpace(raw))
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	return bytes.HasPrefix(head, []byte("<!doctype html")) || bytes.HasPrefix(head, []byte("<html"))
+}
+
+// discoverFeed finds the feed advertised by, or conventionally located next
+// to, the HTML page at pageURL. It returns the parsed feed and its URL.
+func (fs *FeedService) discoverFeed(ctx context.Context, pageURL *url.URL, page []byte) (data.RSSFeed, string, error) {
+	candidates := discoverFeedLinks(page, pageURL)
+	switch {
+	case len(candidates) == 1:
+		feed, err := fs.fetchAndParseFeed(ctx, candidates[0].URL)
+		if err != nil {
+			return data.RSSFeed{}, "", fmt.Errorf("failed to fetch discovered feed %s: %w", candidates[0].URL, err)
+		}
+		return feed, candidates[0].URL, nil
+	case len(candidates) > 1:
+		return data.RSSFeed{}, "", &FeedCandidatesError{Candidates: candidates}
+	}
+
+	for _, path := range commonFeedPaths {
+		probeURL := pageURL.ResolveReference(&url.URL{Path: path}).String()
+		feed, err := fs.fetchAndParseFeed(ctx, probeURL)
+		if err != nil {
+			log.Printf("No feed at %s: %v", probeURL, err)
+			continue
+		}
+		return feed, probeURL, nil
+	}
+
+	return data.RSSFeed{}, "", fmt.Errorf("no feed found on page %s", pageURL)
+}
+
+// fetchAndParseFeed fetches feedURL and parses it, refusing HTML documents.
+func (fs *FeedService) fetchAndParseFeed(ctx context.Context, feedURL string) (data.RSSFeed, error) {
+	resp, err := fs.sendRequest(ctx, feedURL, cacheValidators{})
+	if err != nil {
+		return data.RSSFeed{}, err
+	}
+	defer resp.Body.Close()
+
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType == "text/html" {
+		return data.RSSFeed{}, fmt.Errorf("document is an HTML page, not a feed")
+	}
+	return fs.parseResponse(resp.Body, contentType, feedURL)
+}
+
+// discoverFeedLinks extracts <link rel="alternate"> feed references from an
+// HTML page, resolved against the page's <base href> or its own URL.
+func discoverFeedLinks(page []byte, pageURL *url.URL) []data.FeedCandidate {
+	base := pageURL
+	var candidates []data.FeedCandidate
+	seen := make(map[string]bool)
+
+	for _, token := range utils.TokenizeHTML(string(page)) {
+		if token.Type != utils.HTMLStartTagToken && token.Type != utils.HTMLSelfClosingTagToken {
+			continue
+		}
+		if token.Name == "body" {
+			break
+		}
+		if token.Name == "base" {
+			if href, err := url.Parse(strings.TrimSpace(token.Attr("href"))); err == nil && token.Attr("href") != "" {
+				base = pageURL.ResolveReference(href)
+			}
+			continue
+		}
+		if token.Name != "link" || !hasRel(token.Attr("rel"), "alternate") {
+			continue
+		}
+		linkType := strings.ToLower(strings.TrimSpace(token.Attr("type")))
+		if !feedLinkTypes[linkType] {
+			continue
+		}
+		href, err := url.Parse(strings.TrimSpace(token.Attr("href")))
+		if err != nil || token.Attr("href") == "" {
+			continue
+		}
+		resolved := base.ResolveReference(href).String()
+		if seen[resolved] {
+			continue
+		}
+		seen[resolved] = true
+		candidates = append(candidates, data.FeedCandidate{
+			URL:   resolved,
+			Title: strings.TrimSpace(token.Attr("title")),
+			Type:  linkType,
+		})
+	}
+	return candidates
+}
+
+// hasRel reports whether a space-separated rel attribute contains value.
+func hasRel(rel, value string) bool {
+	for _, field := range strings.Fields(strings.ToLower(rel)) {
+		if field == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestDiscoverFeedLinksIgnoresJSONAPI(t *testing.T) {
+	page := []byte(`<html><head>
+<link rel="alternate" type="application/rss+xml" title="Posts" href="/feed/">
+<link rel="alternate" type="application/json" href="https://example.com/wp-json/wp/v2/pages/2">
+<link rel="https://api.w.org/" href="https://example.com/wp-json/">
+</head><body></body></html>`)
+	pageURL, _ := url.Parse("https://example.com/about/")
+
+	candidates := discoverFeedLinks(page, pageURL)
+	if len(candidates) != 1 {
+		t.Fatalf("got %d candidates, want 1: %+v", len(candidates), candidates)
+	}
+	if candidates[0].URL != "https://example.com/feed/" {
+		t.Errorf("got %s, want https://example.com/feed/", candidates[0].URL)
+	}
+}
+
+func TestDiscoverFeedLinksAcceptsJSONFeed(t *testing.T) {
+	page := []byte(`<html><head>
+<link rel="alternate" type="application/feed+json" href="/feed.json">
+</head></html>`)
+	pageURL, _ := url.Parse("https://example.com/")
+
+	candidates := discoverFeedLinks(page, pageURL)
+	if len(candidates) != 1 || candidates[0].URL != "https://example.com/feed.json" {
+		t.Errorf("got %+v, want the JSON Feed", candidates)
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Rach17/Go-RSS-Aggregator/data"
+	"github.com/Rach17/Go-RSS-Aggregator/db"
+)
+
+// maxLastErrorLength bounds the error message stored with a feed.
+const maxLastErrorLength = 1000
+
+// recordFetchResult stores the outcome of a fetch in the feed's health and
+// schedules its next fetch. A successful fetch of a truncated document is
+// recorded with a warning. After a failure the feed is retried with
+// exponential backoff, or later if the server sent a Retry-After, and after
+// MaxConsecutiveErrors failures in a row it is disabled.
+func (fs *FeedService) recordFetchResult(ctx context.Context, feed db.Feed, truncation data.Truncation, fetchErr error) error {
+	if fetchErr == nil {
+		if truncation.Truncated() {
+			log.Printf("Feed %s: %s", feed.Url, truncation)
+		}
+		now := time.Now()
+		interval := fs.fetchInterval(ctx, feed, now)
+		return fs.FeedRepo.RecordFetchSuccess(ctx, feed.ID, fs.nextFetchAt(feed, now, interval), interval, truncation.String())
+	}
+
+	lastError := fetchErr.Error()
+	if len(lastError) > maxLastErrorLength {
+		lastError = lastError[:maxLastErrorLength]
+	}
+
+	// Being blocked by robots.txt is the site's choice, not a failure of the
+	// feed, so it never disables the feed. Look again once the rules expire.
+	if errors.Is(fetchErr, ErrBlockedByRobots) {
+		log.Printf("Feed %s is blocked by robots.txt", feed.Url)
+		return fs.FeedRepo.RecordFetchFailure(ctx, feed.ID, int(feed.ConsecutiveErrors), lastError, time.Now().Add(fs.RobotsTTL), data.FetchStatusRobotsBlocked)
+	}
+
+	consecutiveErrors := int(feed.ConsecutiveErrors) + 1
+
+	if fs.MaxConsecutiveErrors > 0 && consecutiveErrors >= fs.MaxConsecutiveErrors {
+		log.Printf("Disabling feed %s after %d consecutive errors: %v", feed.Url, consecutiveErrors, fetchErr)
+		return fs.FeedRepo.RecordFetchFailure(ctx, feed.ID, consecutiveErrors, lastError, time.Time{}, data.FetchStatusDisabled)
+	}
+
+	nextRetryAt := time.Now().Add(fs.retryBackoff(consecutiveErrors))
+	// A throttling server says when to come back; never return before that
+	var statusErr *HTTPStatusError
+	if errors.As(fetchErr, &statusErr) && statusErr.RetryAfter > 0 {
+		if retryAt := time.Now().Add(statusErr.RetryAfter); retryAt.After(nextRetryAt) {
+			nextRetryAt = retryAt
+		}
+	}
+	log.Printf("Feed %s failed %d times in a row, retrying after %s", feed.Url, consecutiveErrors, nextRetryAt.Format(time.RFC3339))
+	return fs.FeedRepo.RecordFetchFailure(ctx, feed.ID, consecutiveErrors, lastError, nextRetryAt, data.FetchStatusError)
+}
+
+// retryBackoff returns how long to wait before retrying a feed that has failed
+// consecutiveErrors times in a row.
+func (fs *FeedService) retryBackoff(consecutiveErrors int) time.Duration {
+	backoff := fs.RetryBackoff
+	for i := 1; i < consecutiveErrors; i++ {
+		backoff *= 2
+		if fs.MaxRetryBackoff > 0 && backoff >= fs.MaxRetryBackoff {
+			return fs.MaxRetryBackoff
+		}
+	}
+	return backoff
+}
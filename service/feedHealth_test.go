@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Rach17/Go-RSS-Aggregator/data"
+	"github.com/google/uuid"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	fs := &FeedService{RetryBackoff: 15 * time.Minute, MaxRetryBackoff: 24 * time.Hour}
+	tests := []struct {
+		errors int
+		want   time.Duration
+	}{
+		{1, 15 * time.Minute},
+		{2, 30 * time.Minute},
+		{3, time.Hour},
+		{7, 16 * time.Hour},
+		{8, 24 * time.Hour},
+		{50, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := fs.retryBackoff(tt.errors); got != tt.want {
+			t.Errorf("retryBackoff(%d) = %v, want %v", tt.errors, got, tt.want)
+		}
+	}
+}
+
+func TestRecordFetchResult(t *testing.T) {
+	tests := []struct {
+		name       string
+		errors     int32
+		fetchErr   error
+		wantErrors int32
+		wantStatus string
+		wantRetry  time.Duration
+	}{
+		{"first failure", 0, errors.New("boom"), 1, data.FetchStatusError, 15 * time.Minute},
+		{"third failure", 2, errors.New("boom"), 3, data.FetchStatusError, time.Hour},
+		{"retry after", 0, fmt.Errorf("fetch: %w", &HTTPStatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 3 * time.Hour}), 1, data.FetchStatusError, 3 * time.Hour},
+		{"short retry after", 2, &HTTPStatusError{StatusCode: http.StatusServiceUnavailable, RetryAfter: time.Minute}, 3, data.FetchStatusError, time.Hour},
+		{"disabled", 9, errors.New("boom"), 10, data.FetchStatusDisabled, 0},
+		{"blocked by robots", 4, fmt.Errorf("fetch: %w", ErrBlockedByRobots), 4, data.FetchStatusRobotsBlocked, 24 * time.Hour},
+		{"success", 5, nil, 0, data.FetchStatusOK, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs, feedRepo, _ := newTestFeedService()
+			ctx := context.Background()
+			feed, _ := feedRepo.CreateFeed(ctx, "T", "http://example.com/feed", "", "", "", "", uuid.NullUUID{}, nil)
+			feed.ConsecutiveErrors = tt.errors
+			feedRepo.feeds[feed.ID] = feed
+
+			start := time.Now()
+			if err := fs.recordFetchResult(ctx, feed, data.Truncation{}, tt.fetchErr); err != nil {
+				t.Fatalf("recordFetchResult: %v", err)
+			}
+			stored, _ := feedRepo.GetFeedByID(ctx, feed.ID)
+			if stored.ConsecutiveErrors != tt.wantErrors {
+				t.Errorf("consecutive errors = %d, want %d", stored.ConsecutiveErrors, tt.wantErrors)
+			}
+			if stored.FetchStatus != tt.wantStatus {
+				t.Errorf("status = %q, want %q", stored.FetchStatus, tt.wantStatus)
+			}
+			if tt.fetchErr != nil && !stored.LastError.Valid {
+				t.Error("last error not recorded")
+			}
+			if tt.wantRetry == 0 {
+				if stored.NextRetryAt.Valid {
+					t.Errorf("retry scheduled at %v, want none", stored.NextRetryAt.Time)
+				}
+				return
+			}
+			wait := stored.NextRetryAt.Time.Sub(start)
+			if wait < tt.wantRetry || wait > tt.wantRetry+time.Minute {
+				t.Errorf("retrying after %v, want %v", wait, tt.wantRetry)
+			}
+		})
+	}
+}
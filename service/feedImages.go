@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Rach17/Go-RSS-Aggregator/db"
+	"github.com/Rach17/Go-RSS-Aggregator/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	// feedImageMaxBytes caps the size of a cached feed image or favicon
+	feedImageMaxBytes = 1 << 20
+	// feedImageMaxAge is how long a cached image is served before refetching
+	feedImageMaxAge = 7 * 24 * time.Hour
+	// faviconPageMaxBytes caps how much of a site's home page is read to find its icon
+	faviconPageMaxBytes = 512 << 10
+)
+
+// iconRels are the <link rel> values that point at a site icon, best first.
+var iconRels = []string{"icon", "shortcut icon", "apple-touch-icon"}
+
+// GetFeedImage returns the cached image for a feed and whether the feed is
+// private. The image of a private feed is only returned to its owner, the
+// one user who can follow it; anyone else gets sql.ErrNoRows, as if the feed
+// did not exist. viewer is not set for anonymous requests.
+func (fs *FeedService) GetFeedImage(ctx context.Context, feedID uuid.UUID, viewer uuid.NullUUID) (db.FeedImage, bool, error) {
+	feed, err := fs.FeedRepo.GetFeedByID(ctx, feedID)
+	if err != nil {
+		return db.FeedImage{}, false, fmt.Errorf("failed to get feed: %w", err)
+	}
+	private := feed.OwnerUserID.Valid
+	if private && (!viewer.Valid || viewer.UUID != feed.OwnerUserID.UUID) {
+		return db.FeedImage{}, true, fmt.Errorf("failed to get feed: %w", sql.ErrNoRows)
+	}
+
+	image, err := fs.FeedRepo.GetFeedImage(ctx, feedID)
+	if err != nil {
+		return db.FeedImage{}, private, fmt.Errorf("failed to get feed image: %w", err)
+	}
+	return image, private, nil
+}
+
+// refreshFeedImage caches the feed's image, or the site's favicon when the
+// feed does not provide one. A fresh cached copy of the same image is kept.
+// The image of a private feed is fetched with the feed's credentials when it
+// is on the feed's origin, attached to ctx with withFeedCredentials.
+func (fs *FeedService) refreshFeedImage(ctx context.Context, feed db.Feed) error {
+	sourceURL := feed.ImageUrl.String
+
+	cached, err := fs.FeedRepo.GetFeedImage(ctx, feed.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to get cached feed image: %w", err)
+	}
+	if err == nil && (sourceURL == "" || sourceURL == cached.SourceUrl) {
+		cachedAt := cached.CreatedAt
+		if cached.UpdatedAt.Valid {
+			cachedAt = cached.UpdatedAt.Time
+		}
+		if time.Since(cachedAt) < feedImageMaxAge {
+			return nil
+		}
+	}
+
+	if sourceURL == "" {
+		sourceURL = fs.discoverFavicon(ctx, feed)
+	}
+
+	data, contentType, err := fs.downloadImage(ctx, sourceURL)
+	if err != nil {
+		return fmt.Errorf("failed to download feed image %s: %w", sourceURL, err)
+	}
+
+	if err := fs.FeedRepo.UpsertFeedImage(ctx, feed.ID, sourceURL, contentType, data); err != nil {
+		return fmt.Errorf("failed to cache feed image: %w", err)
+	}
+	return nil
+}
+
+// discoverFavicon finds the icon advertised by the feed's site home page,
+// falling back to /favicon.ico at the site root.
+func (fs *FeedService) discoverFavicon(ctx context.Context, feed db.Feed) string {
+	siteURL, err := url.Parse(feed.SiteUrl.String)
+	if err != nil || !siteURL.IsAbs() {
+		siteURL, err = url.Parse(feed.Url)
+		if err != nil {
+			return ""
+		}
+		siteURL = siteURL.ResolveReference(&url.URL{Path: "/"})
+	}
+	fallback := siteURL.ResolveReference(&url.URL{Path: "/favicon.ico"}).String()
+
+	resp, err := fs.getResource(ctx, siteURL.String(), "text/html")
+	if err != nil {
+		return fallback
+	}
+	defer resp.Body.Close()
+
+	page, err := io.ReadAll(io.LimitReader(resp.Body, faviconPageMaxBytes))
+	if err != nil {
+		return fallback
+	}
+
+	icons := make(map[string]string)
+	for _, token := range utils.TokenizeHTML(string(page)) {
+		if token.Type != utils.HTMLStartTagToken && token.Type != utils.HTMLSelfClosingTagToken {
+			continue
+		}
+		if token.Name == "body" {
+			break
+		}
+		href := strings.TrimSpace(token.Attr("href"))
+		if token.Name != "link" || href == "" {
+			continue
+		}
+		rel := strings.Join(strings.Fields(strings.ToLower(token.Attr("rel"))), " ")
+		if _, seen := icons[rel]; !seen {
+			icons[rel] = href
+		}
+	}
+	for _, rel := range iconRels {
+		if href, ok := icons[rel]; ok {
+			return resolveReference(resp.Request.URL, href).String()
+		}
+	}
+	return fallback
+}
+
+// downloadImage fetches an image, checking its type and size.
+func (fs *FeedService) downloadImage(ctx context.Context, imageURL string) ([]byte, string, error) {
+	if err := fs.validateURL(imageURL); err != nil {
+		return nil, "", err
+	}
+	resp, err := fs.getResource(ctx, imageURL, "image/*")
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, feedImageMaxBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read image: %w", err)
+	}
+	if len(data) > feedImageMaxBytes {
+		return nil, "", fmt.Errorf("image is larger than %d bytes", feedImageMaxBytes)
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, "", fmt.Errorf("unexpected content type %q", contentType)
+	}
+	return data, contentType, nil
+}
+
+// getResource makes a plain GET request for a non-feed resource. Like feed
+// requests, it carries the credentials attached to ctx only if resourceURL is
+// on the origin they were given for.
+func (fs *FeedService) getResource(ctx context.Context, resourceURL, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", resourceURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if err := fs.checkRobots(ctx, req.URL); err != nil {
+		return nil, err
+	}
+	if credentials, ok := feedCredentialsFor(ctx, req.URL); ok {
+		credentials.Apply(req)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", accept)
+
+	resp, err := fs.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s returned status %d", resourceURL, resp.StatusCode)
+	}
+	return resp, nil
+}
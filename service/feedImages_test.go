@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestGetFeedImageVisibility(t *testing.T) {
+	fs, feedRepo, _ := newTestFeedService()
+	ctx := context.Background()
+	owner := uuid.New()
+
+	public, _ := feedRepo.CreateFeed(ctx, "Public", "http://example.com/feed", "", "", "", "", uuid.NullUUID{}, nil)
+	private, _ := feedRepo.CreateFeed(ctx, "Private", "http://example.com/private", "", "", "", "", uuid.NullUUID{UUID: owner, Valid: true}, nil)
+	feedRepo.UpsertFeedImage(ctx, public.ID, "http://example.com/a.png", "image/png", []byte("a"))
+	feedRepo.UpsertFeedImage(ctx, private.ID, "http://example.com/b.png", "image/png", []byte("b"))
+
+	tests := []struct {
+		name        string
+		feedID      uuid.UUID
+		viewer      uuid.NullUUID
+		wantPrivate bool
+		wantFound   bool
+	}{
+		{"public anonymous", public.ID, uuid.NullUUID{}, false, true},
+		{"public other user", public.ID, uuid.NullUUID{UUID: uuid.New(), Valid: true}, false, true},
+		{"private owner", private.ID, uuid.NullUUID{UUID: owner, Valid: true}, true, true},
+		{"private anonymous", private.ID, uuid.NullUUID{}, true, false},
+		{"private other user", private.ID, uuid.NullUUID{UUID: uuid.New(), Valid: true}, true, false},
+		{"unknown feed", uuid.New(), uuid.NullUUID{}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image, isPrivate, err := fs.GetFeedImage(ctx, tt.feedID, tt.viewer)
+			if !tt.wantFound {
+				if !errors.Is(err, sql.ErrNoRows) {
+					t.Fatalf("got %v, want sql.ErrNoRows", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetFeedImage: %v", err)
+			}
+			if isPrivate != tt.wantPrivate {
+				t.Errorf("private = %v, want %v", isPrivate, tt.wantPrivate)
+			}
+			if len(image.Data) == 0 {
+				t.Error("no image data")
+			}
+		})
+	}
+}
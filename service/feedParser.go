@@ -0,0 +1,323 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Rach17/Go-RSS-Aggregator/data"
+	"github.com/Rach17/Go-RSS-Aggregator/utils"
+)
+
+const (
+	atomNamespace = "http://www.w3.org/2005/Atom"
+	rdfNamespace  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+)
+
+// parseResponse detects the format of a feed document and decodes it into
+// the internal RSS representation, with relative URLs resolved against
+// feedURL. contentType is the value of the response's Content-Type header and
+// may be empty. The body is decoded as it is read, one item at a time: at
+// most MaxFeedBytes of it and MaxFeedItems items are read, and what was left
+// out is recorded in the feed's Truncation.
+func (fs *FeedService) parseResponse(body io.Reader, contentType, feedURL string) (data.RSSFeed, error) {
+	capped := newCappedReader(body, fs.MaxFeedBytes)
+	truncation := data.Truncation{ItemLimit: fs.MaxFeedItems}
+
+	feed, err := decodeFeed(capped, contentType, capped.Exceeded, &truncation)
+	if err != nil {
+		return data.RSSFeed{}, err
+	}
+	if capped.Exceeded() {
+		truncation.BodyLimit = fs.MaxFeedBytes
+	}
+	feed.Truncation = truncation
+	resolveRelativeURLs(&feed, feedURL)
+	return feed, nil
+}
+
+// readLimited reads body up to one byte past limit, so a caller can tell a
+// body of exactly limit bytes from a longer one. A limit of 0 reads it all.
+func readLimited(body io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(body)
+	}
+	return io.ReadAll(io.LimitReader(body, limit+1))
+}
+
+// cappedReader reads at most limit bytes of r, like io.LimitReader, and
+// records whether r had more.
+type cappedReader struct {
+	r         io.Reader
+	remaining int64
+	unlimited bool
+	exceeded  bool
+}
+
+// newCappedReader returns a reader of at most limit bytes of r. A limit of 0
+// reads all of r.
+func newCappedReader(r io.Reader, limit int64) *cappedReader {
+	return &cappedReader{r: r, remaining: limit, unlimited: limit <= 0}
+}
+
+// Exceeded reports whether r had more than limit bytes. It is only known
+// once the reader has returned io.EOF.
+func (c *cappedReader) Exceeded() bool {
+	return c.exceeded
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.remaining <= 0 && !c.unlimited {
+		if c.r == nil {
+			return 0, io.EOF
+		}
+		// The limit may have been hit by a limit-sized body: look one byte
+		// further, once, to tell the two apart
+		var probe [1]byte
+		n, _ := io.ReadFull(c.r, probe[:])
+		c.exceeded = n > 0
+		c.r = nil
+		return 0, io.EOF
+	}
+	if !c.unlimited && int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	return n, err
+}
+
+// sniffSize is how much of a body is looked at, without consuming it, to
+// detect its format and check a charset given in Content-Type.
+const sniffSize = 64 << 10
+
+// decodeFeed decodes a feed document of any supported format as it is read
+// from body, so that no more than truncation.ItemLimit items are kept. If
+// cutShort reports that the body ended early, the complete items before the
+// end are returned. The number of skipped items is added to truncation.
+func decodeFeed(body io.Reader, contentType string, cutShort func() bool, truncation *data.Truncation) (data.RSSFeed, error) {
+	buffered := bufio.NewReaderSize(body, sniffSize)
+	if bom, _ := buffered.Peek(3); bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		buffered.Discard(3)
+	}
+	head, _ := buffered.Peek(sniffSize)
+
+	if isJSONFeed(contentType, head) {
+		jsonFeed, skipped, err := decodeJSONFeedStream(buffered, truncation.ItemLimit, cutShort)
+		if err != nil {
+			return data.RSSFeed{}, fmt.Errorf("failed to parse JSON feed: %w", err)
+		}
+		truncation.SkippedItems = skipped
+		var feed data.RSSFeed
+		feed.JSONFeedToRSSFeed(jsonFeed)
+		return feed, nil
+	}
+
+	reader, isUTF8 := utf8Reader(buffered, head, contentType)
+	decoder := newXMLDecoder(reader, isUTF8)
+	root, err := readRootElement(decoder)
+	if err != nil {
+		return data.RSSFeed{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	switch {
+	case root.Name.Local == "rss":
+		var feed data.RSSFeed
+		stream := newItemStream(decoder, root, "item", 2, truncation.ItemLimit, cutShort)
+		if err := xml.NewTokenDecoder(stream).Decode(&feed); err != nil {
+			return data.RSSFeed{}, fmt.Errorf("failed to parse RSS feed: %w", err)
+		}
+		truncation.SkippedItems = stream.skipped
+		return feed, nil
+	case root.Name.Local == "feed" && root.Name.Space == atomNamespace:
+		var atom data.AtomFeed
+		stream := newItemStream(decoder, root, "entry", 1, truncation.ItemLimit, cutShort)
+		if err := xml.NewTokenDecoder(stream).Decode(&atom); err != nil {
+			return data.RSSFeed{}, fmt.Errorf("failed to parse Atom feed: %w", err)
+		}
+		truncation.SkippedItems = stream.skipped
+		var feed data.RSSFeed
+		feed.AtomFeedToRSSFeed(atom)
+		return feed, nil
+	case root.Name.Local == "RDF" && root.Name.Space == rdfNamespace:
+		var rdf data.RDFFeed
+		stream := newItemStream(decoder, root, "item", 1, truncation.ItemLimit, cutShort)
+		if err := xml.NewTokenDecoder(stream).Decode(&rdf); err != nil {
+			return data.RSSFeed{}, fmt.Errorf("failed to parse RDF feed: %w", err)
+		}
+		truncation.SkippedItems = stream.skipped
+		var feed data.RSSFeed
+		feed.RDFFeedToRSSFeed(rdf)
+		return feed, nil
+	default:
+		return data.RSSFeed{}, fmt.Errorf("unsupported feed format: <%s>", root.Name.Local)
+	}
+}
+
+// utf8Reader returns a reader that transcodes body to UTF-8 according to the
+// charset in the Content-Type header, which takes precedence over the XML
+// prolog. It reports whether the result is known to be UTF-8, in which case
+// an encoding declared in the prolog must be ignored. head is the start of
+// body.
+func utf8Reader(body io.Reader, head []byte, contentType string) (io.Reader, bool) {
+	_, params, err := mime.ParseMediaType(contentType)
+	charset := params["charset"]
+	if err != nil || charset == "" {
+		return body, false
+	}
+	if utils.IsUTF8Charset(charset) {
+		// Servers often default to UTF-8 in the header regardless of the
+		// document, so only trust it when the start of the body agrees
+		return body, validUTF8Prefix(head)
+	}
+	if !utils.IsSupportedCharset(charset) {
+		log.Printf("Unsupported charset %q in Content-Type, falling back to the XML prolog", charset)
+		return body, false
+	}
+	reader, err := utils.NewCharsetReader(charset, body)
+	if err != nil {
+		return body, false
+	}
+	return reader, true
+}
+
+// validUTF8Prefix reports whether b is valid UTF-8, allowing it to end in the
+// middle of a character.
+func validUTF8Prefix(b []byte) bool {
+	for i := len(b); i >= 0 && i >= len(b)-utf8.UTFMax+1; i-- {
+		if utf8.Valid(b[:i]) && !utf8.FullRune(b[i:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// newXMLDecoder returns a decoder for r that transcodes the encoding declared
+// in the XML prolog, unless r is already known to be UTF-8.
+func newXMLDecoder(r io.Reader, isUTF8 bool) *xml.Decoder {
+	decoder := xml.NewDecoder(r)
+	if isUTF8 {
+		decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+			return input, nil
+		}
+	} else {
+		decoder.CharsetReader = utils.NewCharsetReader
+	}
+	return decoder
+}
+
+// readRootElement reads decoder up to and including the first element of an
+// XML document, and returns that element.
+func readRootElement(decoder *xml.Decoder) (xml.StartElement, error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Copy(), nil
+		}
+	}
+}
+
+// isJSONFeed reports whether a document should be decoded as a JSON Feed,
+// based on the Content-Type header or, failing that, the start of the body.
+func isJSONFeed(contentType string, head []byte) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if mediaType == "application/feed+json" || mediaType == "application/json" {
+			return true
+		}
+		if strings.Contains(mediaType, "xml") {
+			return false
+		}
+	}
+	trimmed := bytes.TrimSpace(head)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// resolveRelativeURLs makes the channel and item links, enclosures and images
+// of a feed absolute.
+// Relative references are resolved against the nearest xml:base, then the
+// channel <link>, then the URL the feed was fetched from. Each item's XMLBase
+// is replaced by its effective absolute base, for resolving its content.
+func resolveRelativeURLs(feed *data.RSSFeed, feedURL string) {
+	base, err := url.Parse(feedURL)
+	if err != nil {
+		return
+	}
+	base = resolveReference(base, feed.XMLBase)
+	base = resolveReference(base, feed.Channel.XMLBase)
+
+	if feed.Channel.Link != "" {
+		siteURL := resolveReference(base, feed.Channel.Link)
+		feed.Channel.Link = siteURL.String()
+		// Without an explicit xml:base, relative links are relative to the site
+		if feed.XMLBase == "" && feed.Channel.XMLBase == "" {
+			base = siteURL
+		}
+	}
+	feed.Channel.XMLBase = base.String()
+	for _, image := range []*string{&feed.Channel.Image.URL, &feed.Channel.ITunesImage.Href, &feed.Channel.IconURL} {
+		if *image != "" {
+			*image = resolveReference(base, *image).String()
+		}
+	}
+
+	for i := range feed.Channel.Items {
+		item := &feed.Channel.Items[i]
+		itemBase := resolveReference(base, item.XMLBase)
+		if item.Link != "" {
+			item.Link = resolveReference(itemBase, item.Link).String()
+		}
+		for j := range item.Enclosures {
+			if item.Enclosures[j].URL != "" {
+				item.Enclosures[j].URL = resolveReference(itemBase, item.Enclosures[j].URL).String()
+			}
+		}
+		if item.ITunesImage.Href != "" {
+			item.ITunesImage.Href = resolveReference(itemBase, item.ITunesImage.Href).String()
+		}
+		resolveMediaURLs(&item.Media, itemBase)
+		item.XMLBase = itemBase.String()
+	}
+}
+
+func resolveMediaURLs(media *data.Media, base *url.URL) {
+	resolve := func(thumbnails []data.MediaThumbnail, contents []data.MediaContent) {
+		for i := range thumbnails {
+			if thumbnails[i].URL != "" {
+				thumbnails[i].URL = resolveReference(base, thumbnails[i].URL).String()
+			}
+		}
+		for i := range contents {
+			if contents[i].URL != "" {
+				contents[i].URL = resolveReference(base, contents[i].URL).String()
+			}
+		}
+	}
+	resolve(media.MediaThumbnails, media.MediaContents)
+	for _, group := range media.MediaGroups {
+		resolve(group.Thumbnails, group.Contents)
+	}
+}
+
+// resolveReference resolves ref against base, returning base unchanged when
+// ref is empty or not a valid URL reference.
+func resolveReference(base *url.URL, ref string) *url.URL {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return base
+	}
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return base
+	}
+	return base.ResolveReference(parsed)
+}
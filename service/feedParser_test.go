@@ -0,0 +1,257 @@
+package service
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Rach17/Go-RSS-Aggregator/data"
+)
+
+// parseFixture parses a file from testdata as if it had been served from
+// feedURL with the given Content-Type.
+func parseFixture(t *testing.T, name, contentType, feedURL string) data.RSSFeed {
+	t.Helper()
+	file, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	fs, _, _ := newTestFeedService()
+	feed, err := fs.parseResponse(file, contentType, feedURL)
+	if err != nil {
+		t.Fatalf("parseResponse(%s): %v", name, err)
+	}
+	return feed
+}
+
+func TestParseAtomWithMediaContent(t *testing.T) {
+	feed := parseFixture(t, "atom_media.xml", "application/atom+xml", "https://example.com/feed")
+
+	items := feed.Channel.Items
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	if items[0].Title != "Sunset" {
+		t.Errorf("title = %q, want the Atom title, not media:title", items[0].Title)
+	}
+	if items[0].Content != "<p>A sunset.</p>" {
+		t.Errorf("content = %q, want the Atom content, not media:content", items[0].Content)
+	}
+	if got := items[0].ThumbnailURL(); got != "https://example.com/sunset.jpg" {
+		t.Errorf("thumbnail = %q, want the image media:content", got)
+	}
+	if got := items[1].ThumbnailURL(); got != "https://example.com/clip.jpg" {
+		t.Errorf("thumbnail = %q, want the media:group thumbnail", got)
+	}
+}
+
+func TestParseRSSWithITunesImage(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    string
+	}{
+		{"rss_itunes.xml", "https://example.com/artwork.jpg"},
+		{"rss_image_itunes.xml", "https://example.com/logo.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			feed := parseFixture(t, tt.fixture, "application/rss+xml", "https://example.com/feed")
+			if got := feed.Channel.ImageURL(); got != tt.want {
+				t.Errorf("ImageURL() = %q, want %q", got, tt.want)
+			}
+			if got := feed.Channel.ITunesImage.Href; got != "https://example.com/artwork.jpg" {
+				t.Errorf("itunes:image = %q, want the podcast artwork", got)
+			}
+		})
+	}
+
+	feed := parseFixture(t, "rss_itunes.xml", "application/rss+xml", "https://example.com/feed")
+	if got := feed.Channel.Items[0].ThumbnailURL(); got != "https://example.com/ep1.jpg" {
+		t.Errorf("episode thumbnail = %q, want the episode itunes:image", got)
+	}
+}
+
+func TestParseRSSIgnoresForeignNamespaces(t *testing.T) {
+	feed := parseFixture(t, "rss_namespaces.xml", "application/rss+xml", "https://example.com/feed.xml")
+
+	channel := feed.Channel
+	if channel.Title != "Podcast" || channel.Link != "https://example.com/" {
+		t.Errorf("channel title, link = %q, %q, want the RSS elements", channel.Title, channel.Link)
+	}
+	if got := channel.ImageURL(); got != "https://example.com/logo.png" {
+		t.Errorf("ImageURL() = %q, want the RSS image", got)
+	}
+	if got := channel.ITunesImage.Href; got != "https://example.com/artwork.jpg" {
+		t.Errorf("itunes:image = %q, want the podcast artwork", got)
+	}
+
+	if len(channel.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(channel.Items))
+	}
+	item := channel.Items[0]
+	if item.Title != "Episode 1" {
+		t.Errorf("title = %q, want the RSS title, not itunes:title", item.Title)
+	}
+	if item.Link != "https://example.com/ep1" {
+		t.Errorf("link = %q, want the RSS link, not atom:link", item.Link)
+	}
+	if item.Description != "The first episode" {
+		t.Errorf("description = %q, want the RSS description, not media:description", item.Description)
+	}
+	if item.Author != "host@example.com (Host)" {
+		t.Errorf("author = %q, want the RSS author, not itunes:author", item.Author)
+	}
+	if len(item.Categories) != 1 || item.Categories[0] != "Tech" {
+		t.Errorf("categories = %q, want only the RSS category", item.Categories)
+	}
+	if got := item.ThumbnailURL(); got != "https://example.com/ep1.jpg" {
+		t.Errorf("thumbnail = %q, want the episode itunes:image", got)
+	}
+}
+
+func TestParseRDFIgnoresForeignNamespaces(t *testing.T) {
+	body := `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+<channel rdf:about="https://example.com/"><title>RDF</title><link>https://example.com/</link><atom:link rel="self" href="https://example.com/rdf"/></channel>
+<item rdf:about="https://example.com/1"><title>One</title><link>https://example.com/1</link><atom:link rel="self" href="https://example.com/1.xml"/><description>First</description><media:description>Other</media:description></item>
+</rdf:RDF>`
+	fs, _, _ := newTestFeedService()
+	feed, err := fs.parseResponse(strings.NewReader(body), "application/rdf+xml", "https://example.com/rdf")
+	if err != nil {
+		t.Fatalf("parseResponse: %v", err)
+	}
+	if feed.Channel.Link != "https://example.com/" {
+		t.Errorf("channel link = %q, want the RSS 1.0 link", feed.Channel.Link)
+	}
+	if len(feed.Channel.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(feed.Channel.Items))
+	}
+	item := feed.Channel.Items[0]
+	if item.Link != "https://example.com/1" || item.Description != "First" {
+		t.Errorf("item link, description = %q, %q, want the RSS 1.0 elements", item.Link, item.Description)
+	}
+}
+
+func TestParseResponseTruncation(t *testing.T) {
+	rss := `<?xml version="1.0"?><rss version="2.0"><channel><title>T</title>` +
+		`<item><guid>1</guid></item><item><guid>2</guid></item><item><guid>3</guid></item>` +
+		`</channel></rss>`
+	cut := strings.Index(rss, "<item><guid>3") + len("<item><gu")
+	jsonFeed := `{"version":"https://jsonfeed.org/version/1.1","title":"T","items":[` +
+		`{"id":"1"},{"id":"2"},{"id":"3"}]}`
+	jsonCut := strings.Index(jsonFeed, `{"id":"3"`) + 4
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		maxBytes    int64
+		maxItems    int
+		wantItems   int
+		wantLimit   int64
+		wantSkipped int
+	}{
+		{"whole", rss, "application/rss+xml", 0, 0, 3, 0, 0},
+		{"exactly at the limit", rss, "application/rss+xml", int64(len(rss)), 0, 3, 0, 0},
+		{"item limit", rss, "application/rss+xml", 0, 2, 2, 0, 1},
+		{"cut in an item", rss, "application/rss+xml", int64(cut), 0, 2, int64(cut), 0},
+		{"cut and item limit", rss, "application/rss+xml", int64(cut), 1, 1, int64(cut), 1},
+		{"json item limit", jsonFeed, "application/feed+json", 0, 1, 1, 0, 2},
+		{"json cut in an item", jsonFeed, "application/feed+json", int64(jsonCut), 0, 2, int64(jsonCut), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs, _, _ := newTestFeedService()
+			fs.MaxFeedBytes, fs.MaxFeedItems = tt.maxBytes, tt.maxItems
+			feed, err := fs.parseResponse(strings.NewReader(tt.body), tt.contentType, "https://example.com/feed")
+			if err != nil {
+				t.Fatalf("parseResponse: %v", err)
+			}
+			if len(feed.Channel.Items) != tt.wantItems {
+				t.Errorf("got %d items, want %d", len(feed.Channel.Items), tt.wantItems)
+			}
+			if feed.Truncation.BodyLimit != tt.wantLimit {
+				t.Errorf("BodyLimit = %d, want %d", feed.Truncation.BodyLimit, tt.wantLimit)
+			}
+			if feed.Truncation.SkippedItems != tt.wantSkipped {
+				t.Errorf("SkippedItems = %d, want %d", feed.Truncation.SkippedItems, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestParseResponseRejectsCutShortWithoutLimit(t *testing.T) {
+	// A body that simply ends early is malformed, not truncated by us
+	fs, _, _ := newTestFeedService()
+	fs.MaxFeedBytes = 0
+	body := `<rss version="2.0"><channel><item><guid>1</guid></item><item>`
+	if _, err := fs.parseResponse(strings.NewReader(body), "application/rss+xml", "https://example.com/feed"); err == nil {
+		t.Error("parsed a document that ends early")
+	}
+}
+
+func TestParseResponseFormats(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantTitle   string
+		wantErr     bool
+	}{
+		{"rss", "application/rss+xml", `<rss version="2.0"><channel><title>RSS</title><item><title>A</title></item></channel></rss>`, "RSS", false},
+		{"rss as text/xml", "text/xml", `<?xml version="1.0"?><rss><channel><title>RSS</title></channel></rss>`, "RSS", false},
+		{"rss without content type", "", "\xef\xbb\xbf <!-- generator --><rss><channel><title>RSS</title></channel></rss>", "RSS", false},
+		{"atom", "application/atom+xml", `<feed xmlns="http://www.w3.org/2005/Atom"><title>Atom</title><entry><title>A</title></entry></feed>`, "Atom", false},
+		{"rdf", "application/rdf+xml", `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/"><channel><title>RDF</title></channel><item><title>A</title></item></rdf:RDF>`, "RDF", false},
+		{"json feed", "application/feed+json", `{"version":"https://jsonfeed.org/version/1.1","title":"JSON","items":[{"id":"1"}]}`, "JSON", false},
+		{"json sniffed", "text/plain", ` {"version":"https://jsonfeed.org/version/1","title":"JSON","items":[]}`, "JSON", false},
+		{"json served as xml", "application/xml", `{"title":"JSON"}`, "", true},
+		{"atom 0.3", "application/atom+xml", `<feed xmlns="http://purl.org/atom/ns#"><title>Old</title></feed>`, "", true},
+		{"html", "text/html", `<html><head><title>Page</title></head></html>`, "", true},
+		{"empty", "application/rss+xml", ``, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs, _, _ := newTestFeedService()
+			feed, err := fs.parseResponse(strings.NewReader(tt.body), tt.contentType, "https://example.com/feed")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parsed as %+v, want an error", feed.Channel)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseResponse: %v", err)
+			}
+			if feed.Channel.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", feed.Channel.Title, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestParseResponseCharsets(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"charset in header", "text/xml; charset=iso-8859-1", "<rss><channel><title>caf\xe9</title></channel></rss>"},
+		{"charset in prolog", "text/xml", "<?xml version=\"1.0\" encoding=\"windows-1252\"?><rss><channel><title>caf\xe9</title></channel></rss>"},
+		{"header overrides prolog", "text/xml; charset=utf-8", "<?xml version=\"1.0\" encoding=\"iso-8859-1\"?><rss><channel><title>caf\xc3\xa9</title></channel></rss>"},
+		{"wrong utf-8 header", "text/xml; charset=utf-8", "<?xml version=\"1.0\" encoding=\"iso-8859-1\"?><rss><channel><title>caf\xe9</title></channel></rss>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs, _, _ := newTestFeedService()
+			feed, err := fs.parseResponse(strings.NewReader(tt.body), tt.contentType, "https://example.com/feed")
+			if err != nil {
+				t.Fatalf("parseResponse: %v", err)
+			}
+			if feed.Channel.Title != "café" {
+				t.Errorf("title = %q, want %q", feed.Channel.Title, "café")
+			}
+		})
+	}
+}
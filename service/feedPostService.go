@@ -3,10 +3,13 @@ package service
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
+	"github.com/Rach17/Go-RSS-Aggregator/data"
 	"github.com/Rach17/Go-RSS-Aggregator/db"
 	"github.com/Rach17/Go-RSS-Aggregator/repository"
+	"github.com/Rach17/Go-RSS-Aggregator/utils"
 	"github.com/google/uuid"
 )
 
@@ -37,14 +40,143 @@ func (s *FeedPostService) CreateFeedPost(ctx context.Context, feedUrl, title, de
 		return fmt.Errorf("feed does not exist, please create it first")
 	}
 
-	return s.PostRepo.Create(ctx, feed.ID, title, description, url, author, publishedAt)
+	_, err = s.PostRepo.Create(ctx, repository.CreateFeedPostInput{
+		FeedID:            feed.ID,
+		Guid:              url,
+		Title:             title,
+		Description:       utils.SanitizeHTML(description, url),
+		DescriptionRaw:    description,
+		BaseURL:           url,
+		Url:               url,
+		Author:            author,
+		PublishedAt:       publishedAt,
+		PublishedAtSource: data.PublishedAtItem,
+	})
+	return err
 }
 
 
-func (s *FeedPostService) GetFeedPosts(ctx context.Context, feedURL string) ([]db.FeedPost, error) {
-	posts, err := s.PostRepo.GetFeedPosts(ctx, feedURL)
+// GetFeedPosts returns the posts of the feed userID sees at feedURL, with
+// their enclosures and tags.
+func (s *FeedPostService) GetFeedPosts(ctx context.Context, feedURL string, userID uuid.UUID) ([]data.FeedPostDetails, error) {
+	feed, err := s.FeedRepo.GetVisibleFeedByURL(ctx, feedURL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("feed not found: %w", err)
+	}
+
+	posts, err := s.PostRepo.GetFeedPosts(ctx, feed.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get feed posts: %w", err)
 	}
+
+	enclosures, err := s.PostRepo.GetFeedEnclosures(ctx, feed.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed post enclosures: %w", err)
+	}
+	enclosuresByPost := make(map[uuid.UUID][]db.FeedPostEnclosure)
+	for _, enclosure := range enclosures {
+		enclosuresByPost[enclosure.FeedPostID] = append(enclosuresByPost[enclosure.FeedPostID], enclosure)
+	}
+
+	postTags, err := s.PostRepo.GetFeedPostTags(ctx, feed.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed post tags: %w", err)
+	}
+	tagsByPost := make(map[uuid.UUID][]string)
+	for _, postTag := range postTags {
+		tagsByPost[postTag.FeedPostID] = append(tagsByPost[postTag.FeedPostID], postTag.Name)
+	}
+
+	details := make([]data.FeedPostDetails, 0, len(posts))
+	for _, post := range posts {
+		details = append(details, data.FeedPostDetails{
+			FeedPost:   post,
+			Enclosures: enclosuresByPost[post.ID],
+			Tags:       tagsByPost[post.ID],
+		})
+	}
+	return details, nil
+}
+
+// GetFeedTags lists the tags used by the posts of the feed userID sees at
+// feedURL, most used first.
+func (s *FeedPostService) GetFeedTags(ctx context.Context, feedURL string, userID uuid.UUID) ([]db.GetFeedTagsRow, error) {
+	feed, err := s.FeedRepo.GetVisibleFeedByURL(ctx, feedURL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("feed not found: %w", err)
+	}
+
+	tags, err := s.PostRepo.GetFeedTags(ctx, feed.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed tags: %w", err)
+	}
+	return tags, nil
+}
+
+// GetFeedPostsByTag returns the posts carrying a tag in the feeds userID can
+// see, optionally restricted to a single feed. The tag is normalised the same
+// way tags are stored.
+func (s *FeedPostService) GetFeedPostsByTag(ctx context.Context, tag, feedURL string, userID uuid.UUID) ([]db.FeedPost, error) {
+	tag = data.NormalizeTag(tag)
+	if tag == "" {
+		return nil, fmt.Errorf("tag cannot be empty")
+	}
+
+	var feedID uuid.NullUUID
+	if feedURL != "" {
+		feed, err := s.FeedRepo.GetVisibleFeedByURL(ctx, feedURL, userID)
+		if err != nil {
+			return nil, fmt.Errorf("feed not found: %w", err)
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	posts, err := s.PostRepo.GetFeedPostsByTag(ctx, tag, feedID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts by tag: %w", err)
+	}
 	return posts, nil
 }
+
+
+// resanitizePageSize is the number of posts ResanitizeFeedPosts loads at once.
+const resanitizePageSize = 500
+
+// ResanitizeFeedPosts re-runs the HTML sanitiser over the raw content of every
+// stored post, so a policy change also applies to posts ingested before it.
+// Posts are resolved against the base they were sanitised with at ingest;
+// those stored before the base was recorded fall back to their own URL. A
+// post that cannot be updated is logged and skipped. It returns the number
+// of posts updated.
+func (s *FeedPostService) ResanitizeFeedPosts(ctx context.Context) (int, error) {
+	updated, failed := 0, 0
+	afterID := uuid.Nil
+	for {
+		posts, err := s.PostRepo.GetFeedPostsRawContent(ctx, afterID, resanitizePageSize)
+		if err != nil {
+			return updated, fmt.Errorf("failed to get raw post content: %w", err)
+		}
+		for _, post := range posts {
+			baseURL := post.Url
+			if post.BaseUrl.Valid {
+				baseURL = post.BaseUrl.String
+			}
+			description := utils.SanitizeHTML(post.DescriptionRaw.String, baseURL)
+			content := utils.SanitizeHTML(post.ContentRaw.String, baseURL)
+			if err := s.PostRepo.UpdateSanitizedContent(ctx, post.ID, description, content); err != nil {
+				log.Printf("Failed to update post %s: %v", post.ID, err)
+				failed++
+				continue
+			}
+			updated++
+		}
+		if len(posts) < resanitizePageSize {
+			break
+		}
+		afterID = posts[len(posts)-1].ID
+	}
+	if failed > 0 {
+		log.Printf("Failed to resanitize %d posts", failed)
+	}
+	return updated, nil
+}
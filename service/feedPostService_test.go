@@ -0,0 +1,100 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Rach17/Go-RSS-Aggregator/db"
+	"github.com/Rach17/Go-RSS-Aggregator/repository"
+	"github.com/google/uuid"
+)
+
+// rawContentRepo serves stored raw content in ID order and records what
+// ResanitizeFeedPosts writes back.
+type rawContentRepo struct {
+	repository.FeedPostRepository
+
+	rows    []db.GetFeedPostsRawContentRow
+	failing uuid.UUID
+	pages   int
+	updated map[uuid.UUID]string
+}
+
+func (r *rawContentRepo) GetFeedPostsRawContent(ctx context.Context, afterID uuid.UUID, pageSize int) ([]db.GetFeedPostsRawContentRow, error) {
+	r.pages++
+	var page []db.GetFeedPostsRawContentRow
+	for _, row := range r.rows {
+		if bytes.Compare(row.ID[:], afterID[:]) > 0 && len(page) < pageSize {
+			page = append(page, row)
+		}
+	}
+	return page, nil
+}
+
+func (r *rawContentRepo) UpdateSanitizedContent(ctx context.Context, postID uuid.UUID, description, content string) error {
+	if postID == r.failing {
+		return errors.New("update failed")
+	}
+	r.updated[postID] = description
+	return nil
+}
+
+func TestResanitizeFeedPosts(t *testing.T) {
+	repo := &rawContentRepo{updated: make(map[uuid.UUID]string)}
+	for i := 0; i < resanitizePageSize+10; i++ {
+		repo.rows = append(repo.rows, db.GetFeedPostsRawContentRow{
+			ID:             uuid.New(),
+			Url:            "https://example.com/posts/1",
+			BaseUrl:        sql.NullString{String: "https://cdn.example.com/feed/", Valid: true},
+			DescriptionRaw: sql.NullString{String: `<a href="a.html">a</a>`, Valid: true},
+		})
+	}
+	// A post stored before its base was recorded
+	legacy := uuid.New()
+	repo.rows = append(repo.rows, db.GetFeedPostsRawContentRow{
+		ID:             legacy,
+		Url:            "https://example.com/posts/1",
+		DescriptionRaw: sql.NullString{String: `<a href="a.html">a</a>`, Valid: true},
+	})
+	slices.SortFunc(repo.rows, func(a, b db.GetFeedPostsRawContentRow) int {
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+	repo.failing = repo.rows[3].ID
+	if repo.failing == legacy {
+		repo.failing = repo.rows[4].ID
+	}
+
+	s := NewFeedPostService(nil, repo)
+	count, err := s.ResanitizeFeedPosts(context.Background())
+	if err != nil {
+		t.Fatalf("ResanitizeFeedPosts: %v", err)
+	}
+	if want := len(repo.rows) - 1; count != want {
+		t.Errorf("updated %d posts, want %d", count, want)
+	}
+	if repo.pages != 2 {
+		t.Errorf("loaded %d pages, want 2", repo.pages)
+	}
+	for _, row := range repo.rows {
+		description, ok := repo.updated[row.ID]
+		switch {
+		case row.ID == repo.failing:
+			if ok {
+				t.Error("failed post recorded as updated")
+			}
+		case row.ID == legacy:
+			if !strings.Contains(description, "https://example.com/posts/a.html") {
+				t.Errorf("legacy post not resolved against its URL: %s", description)
+			}
+		default:
+			if !strings.Contains(description, "https://cdn.example.com/feed/a.html") {
+				t.Errorf("post not resolved against its stored base: %s", description)
+			}
+		}
+	}
+}
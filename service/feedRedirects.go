@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/Rach17/Go-RSS-Aggregator/db"
+)
+
+// redirectHop is one redirect followed while fetching a feed.
+type redirectHop struct {
+	From   string
+	To     string
+	Status int
+}
+
+// fetchResponse is the response to a feed request together with the
+// redirects that were followed to get it.
+type fetchResponse struct {
+	*http.Response
+	Redirects []redirectHop
+}
+
+// newFetchResponse rebuilds the redirect chain of resp. Each request made for
+// a redirect keeps the response that caused it, so the chain can be walked
+// back from the final request.
+func newFetchResponse(resp *http.Response) *fetchResponse {
+	var hops []redirectHop
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		hops = append([]redirectHop{{
+			From:   req.Response.Request.URL.String(),
+			To:     req.URL.String(),
+			Status: req.Response.StatusCode,
+		}}, hops...)
+	}
+	return &fetchResponse{Response: resp, Redirects: hops}
+}
+
+// PermanentURL returns the URL the feed has permanently moved to: the target
+// of the permanent redirects at the start of the chain. It returns "" when the
+// first redirect is temporary or there was none, as only a permanent move
+// should change the URL we request.
+func (r *fetchResponse) PermanentURL() string {
+	permanentURL := ""
+	for _, hop := range r.Redirects {
+		if hop.Status != http.StatusMovedPermanently && hop.Status != http.StatusPermanentRedirect {
+			break
+		}
+		permanentURL = hop.To
+	}
+	return permanentURL
+}
+
+// migrateFeedURL moves a feed to the URL it has permanently moved to. The old
+// URL stays resolvable as an alias, and if the new URL is already a feed of
+// its own the two are merged. It returns the feed now at newURL.
+func (fs *FeedService) migrateFeedURL(ctx context.Context, feed db.Feed, newURL string) (db.Feed, error) {
+	if err := fs.validateURL(newURL); err != nil {
+		return feed, fmt.Errorf("invalid redirect target %s: %w", newURL, err)
+	}
+
+	moved, err := fs.FeedRepo.MoveFeedURL(ctx, feed.ID, feed.Url, newURL)
+	if err != nil {
+		return feed, fmt.Errorf("failed to move feed to %s: %w", newURL, err)
+	}
+	if moved.ID != feed.ID {
+		log.Printf("Feed %s moved permanently to %s, merged into existing feed %s", feed.Url, newURL, moved.ID)
+	} else {
+		log.Printf("Feed %s moved permanently to %s", feed.Url, newURL)
+	}
+	return moved, nil
+}
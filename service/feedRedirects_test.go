@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPermanentURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		want     string
+	}{
+		{"no redirect", nil, ""},
+		{"moved permanently", []int{http.StatusMovedPermanently}, "/1"},
+		{"permanent redirect", []int{http.StatusPermanentRedirect}, "/1"},
+		{"chain of permanent moves", []int{http.StatusMovedPermanently, http.StatusPermanentRedirect}, "/2"},
+		{"temporary", []int{http.StatusFound}, ""},
+		{"temporary first", []int{http.StatusTemporaryRedirect, http.StatusMovedPermanently}, ""},
+		{"temporary after permanent", []int{http.StatusMovedPermanently, http.StatusFound, http.StatusMovedPermanently}, "/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp fetchResponse
+			for i, status := range tt.statuses {
+				resp.Redirects = append(resp.Redirects, redirectHop{
+					From:   "/" + string(rune('0'+i)),
+					To:     "/" + string(rune('1'+i)),
+					Status: status,
+				})
+			}
+			if got := resp.PermanentURL(); got != tt.want {
+				t.Errorf("PermanentURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// newMovingFeedServer serves a feed at /new and redirects /old to it with
+// the given status.
+func newMovingFeedServer(t *testing.T, status int) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/new", status))
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<rss version="2.0"><channel><title>T</title><item><guid>1</guid></item></channel></rss>`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRefreshFeedFollowsPermanentMove(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		private bool
+		wantURL string
+	}{
+		{"permanent", http.StatusMovedPermanently, false, "/new"},
+		{"permanent redirect", http.StatusPermanentRedirect, false, "/new"},
+		{"temporary", http.StatusFound, false, "/old"},
+		{"private", http.StatusMovedPermanently, true, "/old"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newMovingFeedServer(t, tt.status)
+			fs, feedRepo, _ := newTestFeedService()
+			ctx := context.Background()
+			var owner uuid.NullUUID
+			if tt.private {
+				owner = uuid.NullUUID{UUID: uuid.New(), Valid: true}
+			}
+			feed, _ := feedRepo.CreateFeed(ctx, "T", server.URL+"/old", "", "", "", "", owner, nil)
+
+			if err := fs.RefreshFeed(ctx, feed); err != nil {
+				t.Fatalf("RefreshFeed: %v", err)
+			}
+			stored, _ := feedRepo.GetFeedByID(ctx, feed.ID)
+			if stored.Url != server.URL+tt.wantURL {
+				t.Errorf("feed URL = %s, want %s", stored.Url, server.URL+tt.wantURL)
+			}
+			if !tt.private {
+				if found, err := feedRepo.GetFeedByURL(ctx, server.URL+"/old"); err != nil || found.ID != feed.ID {
+					t.Errorf("old URL resolves to %v (%v), want the feed", found.ID, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRefreshFeedMergesIntoExistingFeed(t *testing.T) {
+	server := newMovingFeedServer(t, http.StatusMovedPermanently)
+	fs, feedRepo, postRepo := newTestFeedService()
+	ctx := context.Background()
+	moving, _ := feedRepo.CreateFeed(ctx, "Old", server.URL+"/old", "", "", "", "", uuid.NullUUID{}, nil)
+	existing, _ := feedRepo.CreateFeed(ctx, "New", server.URL+"/new", "", "", "", "", uuid.NullUUID{}, nil)
+
+	if err := fs.RefreshFeed(ctx, moving); err != nil {
+		t.Fatalf("RefreshFeed: %v", err)
+	}
+	if _, err := feedRepo.GetFeedByID(ctx, moving.ID); err == nil {
+		t.Error("moved feed kept after merging")
+	}
+	if found, _ := feedRepo.GetFeedByURL(ctx, server.URL+"/old"); found.ID != existing.ID {
+		t.Errorf("old URL resolves to %v, want the existing feed %v", found.ID, existing.ID)
+	}
+	if len(postRepo.posts) == 0 {
+		t.Fatal("no posts stored")
+	}
+	for _, post := range postRepo.posts {
+		if post.FeedID != existing.ID {
+			t.Errorf("post stored for feed %v, want the existing feed", post.FeedID)
+		}
+	}
+}
+
+func TestRefreshFeedKeepsURLWhenMoveServesNoFeed(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/parked", http.StatusMovedPermanently))
+	mux.HandleFunc("/parked", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>This domain is for sale</body></html>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	fs, feedRepo, _ := newTestFeedService()
+	ctx := context.Background()
+	feed, _ := feedRepo.CreateFeed(ctx, "T", server.URL+"/old", "", "", "", "", uuid.NullUUID{}, nil)
+
+	if err := fs.RefreshFeed(ctx, feed); err == nil {
+		t.Fatal("refreshed a feed from an HTML page")
+	}
+	stored, _ := feedRepo.GetFeedByID(ctx, feed.ID)
+	if stored.Url != server.URL+"/old" {
+		t.Errorf("feed moved to %s", stored.Url)
+	}
+	if _, err := feedRepo.GetFeedByURL(ctx, server.URL+"/parked"); err == nil {
+		t.Error("redirect target recorded for a page that is not a feed")
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Rach17/Go-RSS-Aggregator/data"
+	"github.com/Rach17/Go-RSS-Aggregator/db"
+	"github.com/Rach17/Go-RSS-Aggregator/repository"
+)
+
+// recentPostsForInterval is how many of a feed's latest posts are used to
+// estimate how often it publishes.
+const recentPostsForInterval = 20
+
+// fetchInterval returns how long to wait between two successful fetches of
+// feed. A feed is polled about twice as often as it has published recently,
+// never sooner than its ttl or sy:updatePeriod ask for, and always within
+// MinFetchInterval and MaxFetchInterval. Feeds without enough dated posts
+// are polled every FetchInterval.
+func (fs *FeedService) fetchInterval(ctx context.Context, feed db.Feed, now time.Time) time.Duration {
+	interval := fs.FetchInterval
+	postTimes, err := fs.PostRepo.GetRecentPostTimes(ctx, feed.ID, recentPostsForInterval)
+	if err != nil {
+		log.Printf("Error getting recent posts of feed %s: %v", feed.Url, err)
+	} else if len(postTimes) >= 2 {
+		// Measure up to now rather than to the newest post, so a feed that
+		// has gone quiet is polled less and less often
+		oldest := postTimes[len(postTimes)-1]
+		interval = now.Sub(oldest) / time.Duration(len(postTimes)) / 2
+	}
+
+	if ttl := time.Duration(feed.TtlMinutes.Int32) * time.Minute; ttl > interval {
+		interval = ttl
+	}
+	if period := time.Duration(feed.UpdatePeriodMinutes.Int32) * time.Minute; period > interval {
+		interval = period
+	}
+
+	if fs.MinFetchInterval > 0 && interval < fs.MinFetchInterval {
+		interval = fs.MinFetchInterval
+	}
+	if fs.MaxFetchInterval > 0 && interval > fs.MaxFetchInterval {
+		interval = fs.MaxFetchInterval
+	}
+	if interval <= 0 {
+		interval = fs.FetchInterval
+	}
+	return interval
+}
+
+// nextFetchAt returns when feed should next be fetched after a successful
+// fetch at fetchedAt. A fetch that would fall in one of the feed's
+// skipHours or skipDays is moved to the next hour it allows.
+func (fs *FeedService) nextFetchAt(feed db.Feed, fetchedAt time.Time, interval time.Duration) time.Time {
+	next := fetchedAt.Add(interval)
+
+	skipHours := make(map[int]bool)
+	for _, hour := range data.ParseSkipHours(strings.Split(feed.SkipHours.String, ",")) {
+		skipHours[hour] = true
+	}
+	skipDays := make(map[time.Weekday]bool)
+	for _, day := range data.ParseSkipDays(strings.Split(feed.SkipDays.String, ",")) {
+		skipDays[day] = true
+	}
+
+	// Both lists are in GMT. Give up after a week in case every hour is skipped.
+	for i := 0; i < 7*24; i++ {
+		utc := next.UTC()
+		if !skipHours[utc.Hour()] && !skipDays[utc.Weekday()] {
+			break
+		}
+		next = utc.Truncate(time.Hour).Add(time.Hour)
+	}
+	return next
+}
+
+// refreshPollHints stores the polling hints of a freshly fetched feed:
+// <ttl>, <skipHours>, <skipDays> and sy:updatePeriod. Unlike the feed's
+// metadata, a hint the document no longer carries is cleared. It returns the
+// updated feed.
+func (fs *FeedService) refreshPollHints(ctx context.Context, feed db.Feed, channel data.Channel) (db.Feed, error) {
+	var hints repository.FeedPollHints
+	if ttl, ok := channel.TTLDuration(); ok {
+		hints.TTLMinutes = int(ttl / time.Minute)
+	}
+	if period, ok := channel.UpdatePeriod(); ok {
+		hints.UpdatePeriodMinutes = int(period / time.Minute)
+	}
+	var hours []string
+	for _, hour := range data.ParseSkipHours(channel.SkipHours) {
+		hours = append(hours, strconv.Itoa(hour))
+	}
+	hints.SkipHours = strings.Join(hours, ",")
+	var days []string
+	for _, day := range data.ParseSkipDays(channel.SkipDays) {
+		days = append(days, day.String())
+	}
+	hints.SkipDays = strings.Join(days, ",")
+
+	if hints.TTLMinutes == int(feed.TtlMinutes.Int32) &&
+		hints.UpdatePeriodMinutes == int(feed.UpdatePeriodMinutes.Int32) &&
+		hints.SkipHours == feed.SkipHours.String &&
+		hints.SkipDays == feed.SkipDays.String {
+		return feed, nil
+	}
+
+	if err := fs.FeedRepo.UpdateFeedPollHints(ctx, feed.ID, hints); err != nil {
+		return feed, err
+	}
+	feed.TtlMinutes = sql.NullInt32{Int32: int32(hints.TTLMinutes), Valid: hints.TTLMinutes > 0}
+	feed.UpdatePeriodMinutes = sql.NullInt32{Int32: int32(hints.UpdatePeriodMinutes), Valid: hints.UpdatePeriodMinutes > 0}
+	feed.SkipHours = sql.NullString{String: hints.SkipHours, Valid: hints.SkipHours != ""}
+	feed.SkipDays = sql.NullString{String: hints.SkipDays, Valid: hints.SkipDays != ""}
+	return feed, nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rach17/Go-RSS-Aggregator/db"
+	"github.com/google/uuid"
+)
+
+// postedEvery returns the times of n posts published every interval before
+// now, newest first.
+func postedEvery(now time.Time, n int, interval time.Duration) []time.Time {
+	var times []time.Time
+	for i := 1; i <= n; i++ {
+		times = append(times, now.Add(-time.Duration(i)*interval))
+	}
+	return times
+}
+
+func TestFetchInterval(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		postTimes []time.Time
+		ttl       int32
+		period    int32
+		want      time.Duration
+	}{
+		{"no posts", nil, 0, 0, time.Hour},
+		{"one post", postedEvery(now, 1, time.Hour), 0, 0, time.Hour},
+		{"hourly posts", postedEvery(now, 10, time.Hour), 0, 0, 30 * time.Minute},
+		{"frequent posts", postedEvery(now, 20, time.Minute), 0, 0, 5 * time.Minute},
+		{"quiet feed", postedEvery(now, 2, 90*24*time.Hour), 0, 0, 24 * time.Hour},
+		{"ttl", postedEvery(now, 10, time.Hour), 180, 0, 3 * time.Hour},
+		{"ttl shorter than posting", postedEvery(now, 10, time.Hour), 10, 0, 30 * time.Minute},
+		{"update period", postedEvery(now, 10, time.Hour), 0, 120, 2 * time.Hour},
+		{"ttl above maximum", nil, 2880, 0, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs, _, postRepo := newTestFeedService()
+			postRepo.postTimes = tt.postTimes
+			feed := db.Feed{
+				ID:                  uuid.New(),
+				TtlMinutes:          sql.NullInt32{Int32: tt.ttl, Valid: tt.ttl > 0},
+				UpdatePeriodMinutes: sql.NullInt32{Int32: tt.period, Valid: tt.period > 0},
+			}
+			if got := fs.fetchInterval(context.Background(), feed, now); got != tt.want {
+				t.Errorf("fetchInterval = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextFetchAt(t *testing.T) {
+	// A Monday
+	fetchedAt := time.Date(2026, time.March, 2, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		skipHours string
+		skipDays  string
+		interval  time.Duration
+		want      time.Time
+	}{
+		{"no hints", "", "", time.Hour, fetchedAt.Add(time.Hour)},
+		{"skipped hours", "11,12", "", time.Hour, time.Date(2026, time.March, 2, 13, 0, 0, 0, time.UTC)},
+		{"allowed hour", "9,10", "", time.Hour, fetchedAt.Add(time.Hour)},
+		{"skipped day", "", "Monday", time.Hour, time.Date(2026, time.March, 3, 0, 0, 0, 0, time.UTC)},
+		{"skipped hours across midnight", "22,23,0,1", "", 12 * time.Hour, time.Date(2026, time.March, 3, 2, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := &FeedService{}
+			feed := db.Feed{
+				SkipHours: sql.NullString{String: tt.skipHours, Valid: tt.skipHours != ""},
+				SkipDays:  sql.NullString{String: tt.skipDays, Valid: tt.skipDays != ""},
+			}
+			if got := fs.nextFetchAt(feed, fetchedAt, tt.interval); !got.Equal(tt.want) {
+				t.Errorf("nextFetchAt = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A feed that skips every hour is still fetched eventually
+	var hours []string
+	for hour := 0; hour < 24; hour++ {
+		hours = append(hours, strconv.Itoa(hour))
+	}
+	fs := &FeedService{}
+	feed := db.Feed{SkipHours: sql.NullString{String: strings.Join(hours, ","), Valid: true}}
+	if got := fs.nextFetchAt(feed, fetchedAt, time.Hour); got.Sub(fetchedAt) > 8*24*time.Hour {
+		t.Errorf("nextFetchAt = %v, want within a week", got)
+	}
+}
+
+func TestRefreshPollHints(t *testing.T) {
+	fs, feedRepo, _ := newTestFeedService()
+	ctx := context.Background()
+	feed, _ := feedRepo.CreateFeed(ctx, "T", "http://example.com/feed", "", "", "", "", uuid.NullUUID{}, nil)
+
+	parsed, err := fs.parseResponse(strings.NewReader(`<rss version="2.0" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"><channel>
+<title>T</title><ttl>90</ttl>
+<skipHours><hour>1</hour><hour>2</hour><hour>24</hour><hour>31</hour></skipHours>
+<skipDays><day>saturday</day><day>Sunday</day><day>Someday</day></skipDays>
+<sy:updatePeriod>daily</sy:updatePeriod><sy:updateFrequency>4</sy:updateFrequency>
+</channel></rss>`), "application/rss+xml", feed.Url)
+	if err != nil {
+		t.Fatalf("parseResponse: %v", err)
+	}
+	feed, err = fs.refreshPollHints(ctx, feed, parsed.Channel)
+	if err != nil {
+		t.Fatalf("refreshPollHints: %v", err)
+	}
+	if feed.TtlMinutes.Int32 != 90 {
+		t.Errorf("ttl = %d, want 90", feed.TtlMinutes.Int32)
+	}
+	if feed.UpdatePeriodMinutes.Int32 != 360 {
+		t.Errorf("update period = %d, want 360", feed.UpdatePeriodMinutes.Int32)
+	}
+	if feed.SkipHours.String != "1,2,0" {
+		t.Errorf("skip hours = %q, want %q", feed.SkipHours.String, "1,2,0")
+	}
+	if feed.SkipDays.String != "Saturday,Sunday" {
+		t.Errorf("skip days = %q, want %q", feed.SkipDays.String, "Saturday,Sunday")
+	}
+
+	// Hints the feed drops are cleared
+	parsed, _ = fs.parseResponse(strings.NewReader(`<rss version="2.0"><channel><title>T</title></channel></rss>`), "application/rss+xml", feed.Url)
+	feed, _ = fs.refreshPollHints(ctx, feed, parsed.Channel)
+	if feed.TtlMinutes.Valid || feed.UpdatePeriodMinutes.Valid || feed.SkipHours.Valid || feed.SkipDays.Valid {
+		t.Errorf("hints kept after the feed dropped them: %+v", feed)
+	}
+}
@@ -1,14 +1,17 @@
 package service
 
 import (
+	"bufio"
 	"context"
-	"encoding/xml"
+	"database/sql"
+	"errors"
 	"fmt"
-	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Rach17/Go-RSS-Aggregator/data"
@@ -18,30 +21,121 @@ import (
 	"github.com/google/uuid"
 )
 
+// userAgent is sent with every request the fetcher makes.
+const userAgent = "RSS-Aggregator/1.0"
+
 type FeedService struct {
 	FeedRepo       repository.FeedRepository
 	PostRepo       repository.FeedPostRepository
 	HTTPClient *http.Client
+
+	// FetchInterval is how often a feed is fetched when its posting
+	// frequency is unknown. Adaptive intervals are kept within
+	// MinFetchInterval and MaxFetchInterval.
+	FetchInterval    time.Duration
+	MinFetchInterval time.Duration
+	MaxFetchInterval time.Duration
+	// MaxConsecutiveErrors is the number of failed fetches in a row after
+	// which a feed is disabled.
+	MaxConsecutiveErrors int
+	// RetryBackoff is the wait before retrying a feed after its first failed
+	// fetch; it doubles with each further failure up to MaxRetryBackoff.
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	// HostLimiter bounds and spaces the requests made to each host.
+	HostLimiter *HostLimiter
+	// RobotsTTL is how long a site's robots.txt is cached.
+	RobotsTTL time.Duration
+	// NetworkPolicy decides which hosts, addresses and ports HTTPClient may
+	// connect to.
+	NetworkPolicy *NetworkPolicy
+	// MaxFeedBytes and MaxFeedItems bound how much of a feed document is
+	// read; zero means no limit.
+	MaxFeedBytes int64
+	MaxFeedItems int
+	// CredentialsKey is the AES-256 key the credentials of private feeds are
+	// encrypted with. Without it, feeds with credentials cannot be added or
+	// fetched.
+	CredentialsKey []byte
+
+	robotsMu      sync.Mutex
+	robots        map[string]robotsEntry
+	robotsLookups map[string]*robotsLookup
 }
 
 func NewFeedService(feedRepo repository.FeedRepository, postRepo repository.FeedPostRepository) *FeedService {
-	return &FeedService{
+	fs := &FeedService{
 		FeedRepo: feedRepo,
 		PostRepo: postRepo,
-		HTTPClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-	}
+		FetchInterval:        60 * time.Minute,
+		MinFetchInterval:     5 * time.Minute,
+		MaxFetchInterval:     24 * time.Hour,
+		MaxConsecutiveErrors: 10,
+		RetryBackoff:         15 * time.Minute,
+		MaxRetryBackoff:      24 * time.Hour,
+		HostLimiter:          NewHostLimiter(2, time.Second),
+		RobotsTTL:            24 * time.Hour,
+		NetworkPolicy:        &NetworkPolicy{},
+		MaxFeedBytes:         10 << 20,
+		MaxFeedItems:         500,
+	}
+
+	// Every connection, including those made for redirects, goes through the
+	// network policy. A proxy would connect on our behalf, so none is used.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		return fs.NetworkPolicy.DialContext(ctx, network, address)
+	}
+	fs.HTTPClient = &http.Client{
+		Timeout:       10 * time.Second,
+		Transport:     transport,
+		CheckRedirect: fs.checkRedirect,
+	}
+	return fs
 }
 
-func (fs *FeedService) CreateFeed(ctx context.Context, feedURL string) (db.Feed, error) {
-	feed, err := fs.ValidateAndFetchNewFeed(ctx, feedURL)
+// CreateFeed fetches and stores a new feed. When owner is set the feed is
+// private to that user and fetched with credentials, which may be empty.
+func (fs *FeedService) CreateFeed(ctx context.Context, feedURL string, owner uuid.NullUUID, credentials data.FeedCredentials) (db.Feed, error) {
+	if !credentials.Empty() && !owner.Valid {
+		return db.Feed{}, fmt.Errorf("%w: only private feeds can have credentials", ErrInvalidFeedCredentials)
+	}
+	if err := credentials.Validate(); err != nil {
+		return db.Feed{}, fmt.Errorf("%w: %v", ErrInvalidFeedCredentials, err)
+	}
+	sealed, err := fs.encryptCredentials(credentials, owner.UUID)
+	if err != nil {
+		return db.Feed{}, fmt.Errorf("failed to encrypt credentials: %w", err)
+	}
+
+	credentialsCtx := withFeedCredentials(ctx, credentials, feedURL)
+	feed, resolvedURL, err := fs.ValidateAndFetchNewFeed(credentialsCtx, feedURL)
 	if err != nil {
 		log.Printf("Error validating and fetching feed: %v", err)
 		return db.Feed{}, fmt.Errorf("failed to validate and fetch feed: %w", err)
 	}
-	// Check if feed already exists
-	exists, err := fs.FeedExists(ctx, feedURL)
+	if resolvedURL != feedURL {
+		log.Printf("Using feed URL %s for %s", resolvedURL, feedURL)
+		// The credentials were given for the URL the user entered. A feed
+		// found on another origin was fetched without them, and must not
+		// be sent them later either.
+		entered, _ := url.Parse(feedURL)
+		resolved, _ := url.Parse(resolvedURL)
+		if sealed != nil && (entered == nil || resolved == nil || !sameOrigin(entered, resolved)) {
+			log.Printf("Not storing credentials for %s, which is not on the origin of %s", resolvedURL, feedURL)
+			sealed = nil
+		}
+		feedURL = resolvedURL
+	}
+	// Check if feed already exists. A private feed only clashes with the
+	// owner's own private feeds.
+	var exists bool
+	if owner.Valid {
+		exists, err = fs.privateFeedExists(ctx, feedURL, owner.UUID)
+	} else {
+		exists, err = fs.FeedExists(ctx, feedURL)
+	}
 	if err != nil {
 		log.Printf("Error checking if feed exists: %v", err)
 	}
@@ -49,45 +143,83 @@ func (fs *FeedService) CreateFeed(ctx context.Context, feedURL string) (db.Feed,
 		log.Printf("Feed already exists: %s", feedURL)
 		return db.Feed{}, fmt.Errorf("feed already exists")
 	}
-	savedFeed, err := fs.FeedRepo.CreateFeed(ctx, feed.Channel.Title, feedURL, feed.Channel.Description, feed.Channel.Language)
+	savedFeed, err := fs.FeedRepo.CreateFeed(ctx, feed.Channel.Title, feedURL, feed.Channel.Description, feed.Channel.Language, feed.Channel.Link, feed.Channel.ImageURL(), owner, sealed)
 	if err != nil {
 		log.Printf("Error creating feed: %v", err)
 		return db.Feed{}, fmt.Errorf("failed to create feed: %w", err)
 	}
 
-	log.Printf("First Post in this feed is about: %s", feed.Channel.Items[0].Title)
+	if len(feed.Channel.Items) > 0 {
+		log.Printf("First Post in this feed is about: %s", feed.Channel.Items[0].Title)
+	}
 	// Create feed posts
-	if err := fs.CreateFeedPosts(ctx, savedFeed.ID, feed.Channel.Items); err != nil {
+	if err := fs.CreateFeedPosts(ctx, savedFeed.ID, feed.Channel.Date(), feed.Channel.Items); err != nil {
 		log.Printf("Error creating feed posts: %v", err)
 		return db.Feed{}, fmt.Errorf("failed to create feed posts: %w", err)
 	}
 
+	if err := fs.refreshFeedImage(credentialsCtx, savedFeed); err != nil {
+		log.Printf("Error caching image for feed %s: %v", feedURL, err)
+	}
+
+	savedFeed, err = fs.refreshPollHints(ctx, savedFeed, feed.Channel)
+	if err != nil {
+		log.Printf("Error storing polling hints for feed %s: %v", feedURL, err)
+	}
+
+	// The feed was just fetched, so schedule its next fetch a full interval away
+	if err := fs.recordFetchResult(ctx, savedFeed, feed.Truncation, nil); err != nil {
+		log.Printf("Error scheduling feed %s: %v", feedURL, err)
+	}
+
 	return savedFeed, nil
 }
 
-func (fs *FeedService) ValidateAndFetchNewFeed(ctx context.Context, feedURL string) (data.RSSFeed, error) {
+// ValidateAndFetchNewFeed fetches and parses the feed at feedURL. When feedURL
+// points at an HTML page, the page's advertised feed is discovered instead.
+// It returns the parsed feed along with the URL it was actually fetched from,
+// which follows any permanent redirect.
+func (fs *FeedService) ValidateAndFetchNewFeed(ctx context.Context, feedURL string) (data.RSSFeed, string, error) {
 	// Validate URL format
 	if err := fs.validateURL(feedURL); err != nil {
 		log.Printf("Invalid URL: %v", err)
-		return data.RSSFeed{}, fmt.Errorf("invalid URL: %w", err)
+		return data.RSSFeed{}, "", fmt.Errorf("invalid URL: %w", err)
 	}
 
 	// Create request with context
-	respBody, err := fs.sendRequest(ctx, feedURL)
+	resp, err := fs.sendRequest(ctx, feedURL, cacheValidators{})
 	if err != nil {
 		log.Printf("Failed to fetch feed: %v", err)
-		return data.RSSFeed{}, fmt.Errorf("failed to fetch feed: %w", err)
+		return data.RSSFeed{}, "", fmt.Errorf("failed to fetch feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// A feed that has moved permanently is stored under its new URL
+	if movedURL := resp.PermanentURL(); movedURL != "" {
+		feedURL = movedURL
+	}
+
+	// A web page is read in full for discovery, a feed is parsed as it is read
+	contentType := resp.Header.Get("Content-Type")
+	body := bufio.NewReader(resp.Body)
+	if head, _ := body.Peek(512); isHTMLDocument(contentType, head) {
+		page, err := readLimited(body, fs.MaxFeedBytes)
+		// Give the host slot back before discovery makes further requests
+		resp.Body.Close()
+		if err != nil {
+			return data.RSSFeed{}, "", fmt.Errorf("failed to read feed: %w", err)
+		}
+		return fs.discoverFeed(ctx, resp.Request.URL, page)
 	}
-	defer respBody.Close()
 
 	// Parse RSS feed
-	feed, err := fs.parseResponse(respBody)
+	feed, err := fs.parseResponse(body, contentType, feedURL)
 	if err != nil {
 		log.Printf("Failed to parse RSS feed: %v", err)
-		return data.RSSFeed{}, fmt.Errorf("failed to parse RSS feed: %w", err)
+		return data.RSSFeed{}, "", fmt.Errorf("failed to parse RSS feed: %w", err)
 	}
 
-	return feed, nil
+	return feed, feedURL, nil
 }
 
 func (fs *FeedService) validateURL(feedURL string) error {
@@ -104,6 +236,10 @@ func (fs *FeedService) validateURL(feedURL string) error {
 		return fmt.Errorf("URL must have a valid host")
 	}
 
+	if err := fs.NetworkPolicy.CheckURL(parsedURL); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -127,40 +263,80 @@ func (fs *FeedService) FeedExists(ctx context.Context, feedURL string) (bool, er
 	return false, nil
 }
 
-func (fs *FeedService) sendRequest(ctx context.Context, feedUrl string) (io.ReadCloser, error) {
+// privateFeedExists reports whether userID already has a private feed at
+// feedURL.
+func (fs *FeedService) privateFeedExists(ctx context.Context, feedURL string, userID uuid.UUID) (bool, error) {
+	feed, err := fs.FeedRepo.GetVisibleFeedByURL(ctx, feedURL, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return feed.OwnerUserID.Valid && feed.Url == feedURL, nil
+}
+
+// cacheValidators are the HTTP validators returned by a previous fetch of a
+// feed, used to make conditional requests.
+type cacheValidators struct {
+	ETag         string
+	LastModified string
+}
+
+// sendRequest fetches a feed, following redirects. The returned response
+// records the redirect chain so permanent moves can be detected. Credentials
+// attached to ctx with withFeedCredentials are sent along if feedUrl is on
+// the origin they were given for.
+func (fs *FeedService) sendRequest(ctx context.Context, feedUrl string, validators cacheValidators) (*fetchResponse, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", feedUrl, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	if err := fs.checkRobots(ctx, req.URL); err != nil {
+		return nil, err
+	}
+
+	if credentials, ok := feedCredentialsFor(ctx, req.URL); ok {
+		credentials.Apply(req)
+	}
+
 	// Set User-Agent header
-	req.Header.Set("User-Agent", "RSS-Aggregator/1.0")
-	req.Header.Set("Accept", "application/rss+xml, application/xml, text/xml")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, text/xml;q=0.9, application/json;q=0.8")
+
+	// Make the request conditional when we have validators from a previous fetch
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
 
 	// Make HTTP request
-	resp, err := fs.HTTPClient.Do(req)
+	resp, err := fs.doRequest(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch feed: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
+	// 304 Not Modified is only expected, and only a success, for conditional requests
+	conditional := validators.ETag != "" || validators.LastModified != ""
+	if resp.StatusCode != http.StatusOK && !(conditional && resp.StatusCode == http.StatusNotModified) {
 		resp.Body.Close()
-		return nil, fmt.Errorf("feed returned status %d", resp.StatusCode)
+		return nil, newHTTPStatusError(resp)
 	}
 
-	return resp.Body, nil
-}
-
-func (fs *FeedService) parseResponse(body io.Reader) (data.RSSFeed, error) {
-	var feed data.RSSFeed
-	if err := xml.NewDecoder(body).Decode(&feed); err != nil {
-		return data.RSSFeed{}, fmt.Errorf("failed to parse response: %w", err)
+	fetched := newFetchResponse(resp)
+	for _, hop := range fetched.Redirects {
+		log.Printf("Redirect %d: %s -> %s", hop.Status, hop.From, hop.To)
 	}
-	return feed, nil
+	return fetched, nil
 }
 
-func (fs *FeedService) GetFeedByURL(ctx context.Context, feedURL string) (db.Feed, error) {
-	feed, err := fs.FeedRepo.GetFeedByURL(ctx, feedURL)
+// GetFeedByURL returns the feed userID sees at feedURL: their own private
+// feed, else the public one.
+func (fs *FeedService) GetFeedByURL(ctx context.Context, feedURL string, userID uuid.UUID) (db.Feed, error) {
+	feed, err := fs.FeedRepo.GetVisibleFeedByURL(ctx, feedURL, userID)
 	if err != nil {
 		return db.Feed{}, fmt.Errorf("failed to get feed by URL: %w", err)
 	}
@@ -168,8 +344,9 @@ func (fs *FeedService) GetFeedByURL(ctx context.Context, feedURL string) (db.Fee
 }
 
 
-func (fs *FeedService) GetAllFeeds(ctx context.Context) ([]db.Feed, error) {
-	feeds, err := fs.FeedRepo.GetAllFeeds(ctx)
+// GetAllFeeds returns the public feeds and the private feeds of userID.
+func (fs *FeedService) GetAllFeeds(ctx context.Context, userID uuid.UUID) ([]db.Feed, error) {
+	feeds, err := fs.FeedRepo.GetAllFeeds(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all feeds: %w", err)
 	}
@@ -177,75 +354,200 @@ func (fs *FeedService) GetAllFeeds(ctx context.Context) ([]db.Feed, error) {
 }
 
 
-func (fs *FeedService) UpdateFeed(ctx context.Context, url string) error {
-	feed, err := fs.FeedRepo.GetFeedByURL(ctx, url)
+// UpdateFeed refreshes the feed userID sees at url.
+func (fs *FeedService) UpdateFeed(ctx context.Context, url string, userID uuid.UUID) error {
+	feed, err := fs.FeedRepo.GetVisibleFeedByURL(ctx, url, userID)
 	if err != nil {
 		return fmt.Errorf("failed to get feed by URL: %w", err)
 	}
+	return fs.RefreshFeed(ctx, feed)
+}
+
+// RefreshFeed fetches a feed, stores its new posts and records the outcome in
+// the feed's fetch health.
+func (fs *FeedService) RefreshFeed(ctx context.Context, feed db.Feed) error {
+	feed, truncation, fetchErr := fs.fetchFeed(ctx, feed)
+	if err := fs.recordFetchResult(ctx, feed, truncation, fetchErr); err != nil {
+		log.Printf("Failed to record fetch result for feed %s: %v", feed.Url, err)
+	}
+	return fetchErr
+}
 
-	respBody, err := fs.sendRequest(ctx, feed.Url)
+// fetchFeed fetches a feed and stores its new posts. It returns the feed as
+// stored afterwards, which differs from feed if the feed has moved, and what
+// was left out of the document if it broke the size limits.
+func (fs *FeedService) fetchFeed(ctx context.Context, feed db.Feed) (db.Feed, data.Truncation, error) {
+	credentials, err := fs.loadFeedCredentials(ctx, feed)
+	if err != nil {
+		return feed, data.Truncation{}, fmt.Errorf("failed to load feed credentials: %w", err)
+	}
+	credentialsCtx := withFeedCredentials(ctx, credentials, feed.Url)
+	resp, err := fs.sendRequest(credentialsCtx, feed.Url, cacheValidators{
+		ETag:         feed.Etag.String,
+		LastModified: feed.LastModified.String,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to fetch feed: %w", err)
+		return feed, data.Truncation{}, fmt.Errorf("failed to fetch feed: %w", err)
 	}
-	defer respBody.Close()
+	defer resp.Body.Close()
 
-	// Parse RSS feed
-	fetchedFeed, err := fs.parseResponse(respBody)
+	// A 304 carries no document to vouch for a permanent move, so the move
+	// waits for the next full fetch
+	if resp.StatusCode == http.StatusNotModified {
+		log.Printf("No new updates for feed: %s", feed.Title)
+		if err := fs.FeedRepo.UpdateFeedLastFetchedAt(ctx, feed.ID); err != nil {
+			return feed, data.Truncation{}, fmt.Errorf("failed to update feed last fetched time: %w", err)
+		}
+		return feed, data.Truncation{}, nil
+	}
+
+	// Parse RSS feed, resolving relative links against the URL it moved to
+	feedURL := feed.Url
+	movedURL := resp.PermanentURL()
+	if movedURL != "" {
+		feedURL = movedURL
+	}
+	fetchedFeed, err := fs.parseResponse(resp.Body, resp.Header.Get("Content-Type"), feedURL)
+	// The body is read; give the host slot back before the image is fetched
+	// from what may be the same host
+	resp.Body.Close()
 	if err != nil {
-		return fmt.Errorf("failed to parse RSS feed: %w", err)
+		return feed, data.Truncation{}, fmt.Errorf("failed to parse RSS feed: %w", err)
 	}
-	
-	f := data.RSSFeed{}
-	f.DbFeedToRSSFeed(feed)
 
-	if (f.Channel.LastBuildDate >= fetchedFeed.Channel.LastBuildDate) {
-		log.Printf("No new updates for feed: %s", fetchedFeed.Channel.Title)
-		return nil
+	// Follow a permanent move for good; temporary redirects change nothing.
+	// The move is only trusted once the new URL has served a feed, so a
+	// redirect to a parked domain or a home page cannot take the feed with it.
+	// Aliases and merges are shared by everyone, so private feeds keep their
+	// URL and simply follow the redirect on every fetch.
+	if movedURL != "" && movedURL != feed.Url && !feed.OwnerUserID.Valid {
+		feed, err = fs.migrateFeedURL(ctx, feed, movedURL)
+		if err != nil {
+			return feed, data.Truncation{}, fmt.Errorf("failed to migrate feed URL: %w", err)
+		}
+	}
+
+	// Keep the feed's metadata current and refresh the cached image
+	feed, err = fs.refreshFeedMetadata(ctx, feed, fetchedFeed.Channel)
+	if err != nil {
+		return feed, fetchedFeed.Truncation, fmt.Errorf("failed to update feed metadata: %w", err)
+	}
+	if err := fs.refreshFeedImage(credentialsCtx, feed); err != nil {
+		log.Printf("Error caching image for feed %s: %v", feed.Url, err)
+	}
+	feed, err = fs.refreshPollHints(ctx, feed, fetchedFeed.Channel)
+	if err != nil {
+		return feed, fetchedFeed.Truncation, fmt.Errorf("failed to update feed polling hints: %w", err)
 	}
 
 	if len(fetchedFeed.Channel.Items) == 0 {
 		log.Printf("No items found in feed: %s", fetchedFeed.Channel.Title)
-		return nil
+		return feed, fetchedFeed.Truncation, nil
 	}
 
 
-	existingPosts, err := fs.PostRepo.GetFeedPostsUrlAndTitle(ctx, feed.ID)
+	existingPosts, err := fs.PostRepo.GetFeedPostGuids(ctx, feed.ID)
 	if err != nil {
-		return fmt.Errorf("failed to get existing posts: %w", err)
+		return feed, fetchedFeed.Truncation, fmt.Errorf("failed to get existing posts: %w", err)
 	}
 
+	var newItems []data.FeedPost
 	for _, item := range fetchedFeed.Channel.Items {
 		// Check if the post already exists
-		if _, exists := existingPosts[item.Link]; exists {
-			log.Printf("Post already exists: %s", item.Title)
+		if existingPosts[item.Identity()] {
 			continue
 		}
+		newItems = append(newItems, item)
+	}
+	log.Printf("Found %d new posts in feed: %s", len(newItems), fetchedFeed.Channel.Title)
 
-		pubAtdate, err := utils.ParseRSSDate(item.PublishedAt)
-		if err != nil {
-			log.Printf("Failed to parse date for item %s: %v", item.Title,
-				err)
-			// Use current time as fallback
-			pubAtdate = time.Now()
-		}
-		// Create new post
-		if err := fs.PostRepo.Create(ctx, feed.ID, item.Title, item.Description, item.Link, item.Author, pubAtdate); err != nil {
-			return fmt.Errorf("failed to create post: %w", err)
-		}
+	if err := fs.CreateFeedPosts(ctx, feed.ID, fetchedFeed.Channel.Date(), newItems); err != nil {
+		return feed, fetchedFeed.Truncation, fmt.Errorf("failed to create posts: %w", err)
 	}
 
 	// Update feed last fetched time
-	if err := fs.FeedRepo.UpdateFeedLastFetchedAt(ctx, url); err != nil {
-		return fmt.Errorf("failed to update feed last fetched time: %w", err)
+	if err := fs.FeedRepo.UpdateFeedLastFetchedAt(ctx, feed.ID); err != nil {
+		return feed, fetchedFeed.Truncation, fmt.Errorf("failed to update feed last fetched time: %w", err)
+	}
+
+	// Remember the validators so the next fetch can be conditional
+	if err := fs.FeedRepo.UpdateFeedCacheValidators(ctx, feed.ID, resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")); err != nil {
+		return feed, fetchedFeed.Truncation, fmt.Errorf("failed to update feed cache validators: %w", err)
 	}
 
 
 	log.Printf("Successfully fetched and updated feed: %s", fetchedFeed.Channel.Title)
-	return nil
+	return feed, fetchedFeed.Truncation, nil
+}
+
+// refreshFeedMetadata updates the stored metadata of a feed from a freshly
+// fetched copy and records each change in the feed's history. Fields the
+// document leaves out keep their stored value. It returns the updated feed.
+func (fs *FeedService) refreshFeedMetadata(ctx context.Context, feed db.Feed, channel data.Channel) (db.Feed, error) {
+	current := repository.FeedMetadata{
+		Title:       feed.Title,
+		Description: feed.Description.String,
+		Language:    feed.Language,
+		SiteURL:     feed.SiteUrl.String,
+		ImageURL:    feed.ImageUrl.String,
+	}
+	updated := current
+
+	var events []data.FeedEvent
+	changes := []struct {
+		eventType string
+		field     *string
+		value     string
+	}{
+		{data.FeedEventTitleChanged, &updated.Title, channel.Title},
+		{data.FeedEventDescriptionChanged, &updated.Description, channel.Description},
+		{data.FeedEventLanguageChanged, &updated.Language, channel.Language},
+		{data.FeedEventSiteURLChanged, &updated.SiteURL, channel.Link},
+		{data.FeedEventImageChanged, &updated.ImageURL, channel.ImageURL()},
+	}
+	for _, change := range changes {
+		value := strings.TrimSpace(change.value)
+		if value == "" || value == strings.TrimSpace(*change.field) {
+			continue
+		}
+		events = append(events, data.FeedEvent{Type: change.eventType, OldValue: *change.field, NewValue: value})
+		*change.field = value
+	}
+	if len(events) == 0 {
+		return feed, nil
+	}
+
+	if err := fs.FeedRepo.UpdateFeedMetadata(ctx, feed.ID, updated, events); err != nil {
+		return feed, err
+	}
+	for _, event := range events {
+		log.Printf("Feed %s: %s from %q to %q", feed.Url, event.Type, event.OldValue, event.NewValue)
+	}
+
+	feed.Title = updated.Title
+	feed.Description = sql.NullString{String: updated.Description, Valid: updated.Description != ""}
+	feed.Language = updated.Language
+	feed.SiteUrl = sql.NullString{String: updated.SiteURL, Valid: updated.SiteURL != ""}
+	feed.ImageUrl = sql.NullString{String: updated.ImageURL, Valid: updated.ImageURL != ""}
+	return feed, nil
+}
+
+// GetFeedHistory returns the recorded changes of the feed userID sees at
+// feedURL, newest first.
+func (fs *FeedService) GetFeedHistory(ctx context.Context, feedURL string, userID uuid.UUID) ([]db.FeedEvent, error) {
+	feed, err := fs.FeedRepo.GetVisibleFeedByURL(ctx, feedURL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed by URL: %w", err)
+	}
+	events, err := fs.FeedRepo.GetFeedEvents(ctx, feed.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed history: %w", err)
+	}
+	return events, nil
 }
 
 func (fs *FeedService) FollowFeed(ctx context.Context, feedURL string, userID uuid.UUID)  error {
-	feed, err := fs.FeedRepo.GetFeedByURL(ctx, feedURL)
+	feed, err := fs.FeedRepo.GetVisibleFeedByURL(ctx, feedURL, userID)
 	if err != nil {
 		if strings.Contains(err.Error(), "no rows in result set") || strings.Contains(err.Error(), "sql: no rows") {
 			return fmt.Errorf("feed does not exist: %w", err)
@@ -264,8 +566,14 @@ func (fs *FeedService) FollowFeed(ctx context.Context, feedURL string, userID uu
 	return nil
 }
 
-func (fs *FeedService) CreateAndFollowFeed(ctx context.Context, feedURL string, userID uuid.UUID) (db.Feed, error) {
-	feed, err := fs.CreateFeed(ctx, feedURL)
+// CreateAndFollowFeed creates a feed and makes userID follow it. A private
+// feed, or one with credentials, belongs to userID alone.
+func (fs *FeedService) CreateAndFollowFeed(ctx context.Context, feedURL string, userID uuid.UUID, private bool, credentials data.FeedCredentials) (db.Feed, error) {
+	var owner uuid.NullUUID
+	if private || !credentials.Empty() {
+		owner = uuid.NullUUID{UUID: userID, Valid: true}
+	}
+	feed, err := fs.CreateFeed(ctx, feedURL, owner, credentials)
 	if err != nil {
 		return db.Feed{}, fmt.Errorf("failed to create feed: %w", err)
 	}
@@ -279,17 +587,91 @@ func (fs *FeedService) CreateAndFollowFeed(ctx context.Context, feedURL string,
 }
 
 
-func (fs *FeedService) CreateFeedPosts(ctx context.Context, feedID uuid.UUID, items []data.FeedPost) error {
+// CreateFeedPosts stores items for a feed. Items the feed already has are
+// skipped, and a failure to store one item does not prevent storing the rest.
+// feedDate is the feed's own last-changed date, used for undated items.
+func (fs *FeedService) CreateFeedPosts(ctx context.Context, feedID uuid.UUID, feedDate string, items []data.FeedPost) error {
+	failed := 0
+	seenAt := time.Now()
 	for _, item := range items {
-		pubAtdate, err := utils.ParseRSSDate(item.PublishedAt)
-        if err != nil {
-            log.Printf("Failed to parse date for item %s: %v", item.Title, err)
-            // Use current time as fallback
-            pubAtdate = time.Now()
-        }
-		if err := fs.PostRepo.Create(ctx, feedID, item.Title, item.Description, item.Link, item.Author, pubAtdate); err != nil {
-			return fmt.Errorf("failed to create post: %w", err)
+		pubAtdate, pubAtSource := postPublishedAt(item, feedDate, seenAt)
+		post := repository.CreateFeedPostInput{
+			FeedID:            feedID,
+			Guid:              item.Identity(),
+			Title:             item.Title,
+			Description:       utils.SanitizeHTML(item.Description, item.XMLBase),
+			DescriptionRaw:    item.Description,
+			Content:           utils.SanitizeHTML(item.Content, item.XMLBase),
+			ContentRaw:        item.Content,
+			BaseURL:           item.XMLBase,
+			Url:               item.Link,
+			Author:            item.Author,
+			PublishedAt:       pubAtdate,
+			PublishedAtSource: pubAtSource,
+			ItunesImage:       item.ITunesImage.Href,
+			ThumbnailUrl:      item.ThumbnailURL(),
+		}
+		if duration, ok := item.DurationSeconds(); ok {
+			post.ItunesDurationSeconds = sql.NullInt32{Int32: int32(duration), Valid: true}
+		}
+		if episode, ok := item.Episode(); ok {
+			post.ItunesEpisode = sql.NullInt32{Int32: int32(episode), Valid: true}
+		}
+		if season, ok := item.Season(); ok {
+			post.ItunesSeason = sql.NullInt32{Int32: int32(season), Valid: true}
+		}
+		if explicit, ok := item.Explicit(); ok {
+			post.ItunesExplicit = sql.NullBool{Bool: explicit, Valid: true}
+		}
+
+		postID, err := fs.PostRepo.Create(ctx, post)
+		if err != nil {
+			log.Printf("Failed to create post %s: %v", item.Title, err)
+			failed++
+			continue
 		}
+		if postID == uuid.Nil {
+			// The feed already has this post
+			continue
+		}
+
+		for _, enclosure := range item.Enclosures {
+			if enclosure.URL == "" {
+				continue
+			}
+			length, _ := enclosure.LengthBytes()
+			if err := fs.PostRepo.CreateEnclosure(ctx, postID, enclosure.URL, enclosure.Type, length); err != nil {
+				log.Printf("Failed to create enclosure %s for post %s: %v", enclosure.URL, item.Title, err)
+			}
+		}
+
+		for _, tag := range item.Tags() {
+			if err := fs.PostRepo.AddTag(ctx, postID, tag); err != nil {
+				log.Printf("Failed to tag post %s with %q: %v", item.Title, tag, err)
+			}
+		}
+	}
+	if failed > 0 && failed == len(items) {
+		return fmt.Errorf("failed to create any of the %d posts", failed)
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// postPublishedAt picks the publication date of an item: its own date, else
+// the feed's last-changed date, else the time it was first seen. It also
+// returns which of these was used.
+func postPublishedAt(item data.FeedPost, feedDate string, seenAt time.Time) (time.Time, string) {
+	if date := item.Date(); date != "" {
+		publishedAt, err := utils.ParseRSSDate(date)
+		if err == nil {
+			return publishedAt, data.PublishedAtItem
+		}
+		log.Printf("Failed to parse date for item %s: %v", item.Title, err)
+	}
+	if feedDate != "" {
+		if publishedAt, err := utils.ParseRSSDate(feedDate); err == nil {
+			return publishedAt, data.PublishedAtFeed
+		}
+	}
+	return seenAt, data.PublishedAtFirstSeen
+}
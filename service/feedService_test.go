@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Rach17/Go-RSS-Aggregator/data"
+	"github.com/google/uuid"
+)
+
+// newStaticFeedServer serves body as an RSS document at /feed.
+func newStaticFeedServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(body))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRefreshFeedSkipsKnownPosts(t *testing.T) {
+	// Two items share a link but not a guid, and two have neither
+	server := newStaticFeedServer(t, `<rss version="2.0"><channel><title>T</title>
+<item><title>A</title><guid>urn:a</guid><link>https://example.com/shared</link></item>
+<item><title>B</title><guid>urn:b</guid><link>https://example.com/shared</link></item>
+<item><title>C</title><link>https://example.com/c</link></item>
+<item><title>D</title><description>first</description></item>
+<item><title>E</title><description>second</description></item>
+</channel></rss>`)
+
+	fs, feedRepo, postRepo := newTestFeedService()
+	ctx := context.Background()
+	feed, _ := feedRepo.CreateFeed(ctx, "T", server.URL+"/feed", "", "", "", "", uuid.NullUUID{}, nil)
+
+	if err := fs.RefreshFeed(ctx, feed); err != nil {
+		t.Fatalf("first refresh: %v", err)
+	}
+	want := []string{"urn:a", "urn:b", "https://example.com/c"}
+	if len(postRepo.posts) != 5 {
+		t.Fatalf("stored %d posts, want 5", len(postRepo.posts))
+	}
+	for i, guid := range want {
+		if postRepo.posts[i].Guid != guid {
+			t.Errorf("post %d guid = %q, want %q", i, postRepo.posts[i].Guid, guid)
+		}
+	}
+	if postRepo.posts[3].Guid == postRepo.posts[4].Guid {
+		t.Error("items without guid or link share an identity")
+	}
+
+	if err := fs.RefreshFeed(ctx, feed); err != nil {
+		t.Fatalf("second refresh: %v", err)
+	}
+	if len(postRepo.posts) != 5 {
+		t.Errorf("stored %d posts after refetching, want 5", len(postRepo.posts))
+	}
+
+	// Another feed syndicating the same article keeps its own copy
+	other, _ := feedRepo.CreateFeed(ctx, "Other", server.URL+"/feed?copy", "", "", "", "", uuid.NullUUID{}, nil)
+	if err := fs.CreateFeedPosts(ctx, other.ID, "", []data.FeedPost{{Title: "A", GUID: "urn:a"}}); err != nil {
+		t.Fatalf("CreateFeedPosts: %v", err)
+	}
+	if len(postRepo.posts) != 6 {
+		t.Errorf("stored %d posts, want the other feed's copy too", len(postRepo.posts))
+	}
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Rach17/Go-RSS-Aggregator/data"
+)
+
+// itemStream is an xml.TokenReader that passes a feed document through one
+// item at a time, so the document can be decoded into the usual structs
+// without ever holding more than maxItems items. Items beyond maxItems are
+// skipped without being decoded. When the body turns out to be cut short,
+// the item being read when it ends is dropped and the open elements are
+// closed, so the complete items before it can still be decoded.
+type itemStream struct {
+	decoder   *xml.Decoder
+	itemName  string
+	itemDepth int
+	maxItems  int
+	cutShort  func() bool
+
+	open    []xml.Name
+	queue   []xml.Token
+	items   int
+	skipped int
+	done    bool
+}
+
+// newItemStream streams the rest of the document read by decoder, which has
+// just read its root element. Items are the elements named itemName nested
+// itemDepth elements deep. A maxItems of 0 keeps all items. cutShort reports
+// whether the body ended before the document did.
+func newItemStream(decoder *xml.Decoder, root xml.StartElement, itemName string, itemDepth, maxItems int, cutShort func() bool) *itemStream {
+	return &itemStream{
+		decoder:   decoder,
+		itemName:  itemName,
+		itemDepth: itemDepth,
+		maxItems:  maxItems,
+		cutShort:  cutShort,
+		open:      []xml.Name{root.Name},
+		queue:     []xml.Token{root},
+	}
+}
+
+func (s *itemStream) Token() (xml.Token, error) {
+	for len(s.queue) == 0 {
+		if s.done {
+			return nil, io.EOF
+		}
+		if err := s.fill(); err != nil {
+			return nil, err
+		}
+	}
+	token := s.queue[0]
+	s.queue = s.queue[1:]
+	return token, nil
+}
+
+// fill queues the next token of the document, or a whole item.
+func (s *itemStream) fill() error {
+	token, err := s.decoder.Token()
+	if err != nil {
+		return s.end(err)
+	}
+
+	switch t := token.(type) {
+	case xml.StartElement:
+		if len(s.open) == s.itemDepth && t.Name.Local == s.itemName {
+			return s.readItem(t)
+		}
+		s.open = append(s.open, t.Name)
+	case xml.EndElement:
+		if len(s.open) > 0 {
+			s.open = s.open[:len(s.open)-1]
+		}
+	}
+	s.queue = append(s.queue, xml.CopyToken(token))
+	return nil
+}
+
+// readItem queues the item that starts with start once it has been read in
+// full, or skips it if the item limit has been reached.
+func (s *itemStream) readItem(start xml.StartElement) error {
+	if s.maxItems > 0 && s.items >= s.maxItems {
+		if err := s.decoder.Skip(); err != nil {
+			return s.end(err)
+		}
+		s.skipped++
+		return nil
+	}
+
+	item := []xml.Token{start.Copy()}
+	for depth := 1; depth > 0; {
+		token, err := s.decoder.Token()
+		if err != nil {
+			return s.end(err)
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+		item = append(item, xml.CopyToken(token))
+	}
+	s.items++
+	s.queue = append(s.queue, item...)
+	return nil
+}
+
+// end handles the end of the underlying document. A document that was cut
+// short is completed by closing its open elements; otherwise err is returned.
+func (s *itemStream) end(err error) error {
+	if err == io.EOF || !s.cutShort() {
+		return err
+	}
+	for i := len(s.open) - 1; i >= 0; i-- {
+		s.queue = append(s.queue, xml.EndElement{Name: s.open[i]})
+	}
+	s.open = nil
+	s.done = true
+	return nil
+}
+
+// decodeJSONFeedStream decodes a JSON Feed one item at a time, keeping at most
+// maxItems items (0 keeps all). When cutShort reports that the body was cut
+// short, the items and fields read before the cut are kept. It returns the
+// number of items skipped.
+func decodeJSONFeedStream(body io.Reader, maxItems int, cutShort func() bool) (data.JSONFeed, int, error) {
+	var jsonFeed data.JSONFeed
+	fields := make(map[string]json.RawMessage)
+	var items []data.JSONFeedItem
+	skipped := 0
+
+	decoder := json.NewDecoder(body)
+	err := func() error {
+		if token, err := decoder.Token(); err != nil {
+			return err
+		} else if token != json.Delim('{') {
+			return fmt.Errorf("expected a JSON object")
+		}
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := token.(string)
+			if !strings.EqualFold(key, "items") {
+				var value json.RawMessage
+				if err := decoder.Decode(&value); err != nil {
+					return err
+				}
+				fields[key] = value
+				continue
+			}
+
+			if token, err := decoder.Token(); err != nil {
+				return err
+			} else if token != json.Delim('[') {
+				return fmt.Errorf("expected items to be an array")
+			}
+			for decoder.More() {
+				if maxItems > 0 && len(items) >= maxItems {
+					var skip json.RawMessage
+					if err := decoder.Decode(&skip); err != nil {
+						return err
+					}
+					skipped++
+					continue
+				}
+				var item data.JSONFeedItem
+				if err := decoder.Decode(&item); err != nil {
+					return err
+				}
+				items = append(items, item)
+			}
+			if _, err := decoder.Token(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil && (!cutShort() || (len(fields) == 0 && len(items) == 0)) {
+		return data.JSONFeed{}, 0, err
+	}
+
+	// Decode the remaining fields through the usual struct tags
+	rest, err := json.Marshal(fields)
+	if err != nil {
+		return data.JSONFeed{}, 0, err
+	}
+	if err := json.Unmarshal(rest, &jsonFeed); err != nil {
+		return data.JSONFeed{}, 0, err
+	}
+	jsonFeed.Items = items
+	return jsonFeed, skipped, nil
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HostLimiter keeps the fetcher polite towards the sites it polls. It bounds
+// the number of requests in flight to each host and spaces the start of
+// requests to a host at least MinDelay apart. The settings may be changed
+// until the first request is made; zero disables a limit. A host is
+// forgotten once no request holds or waits for it and MinDelay has passed
+// since its last request started.
+type HostLimiter struct {
+	MaxConcurrent int
+	MinDelay      time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	slots     chan struct{}
+	nextStart time.Time
+	// users counts the requests holding or waiting for a slot; l.mu guards it.
+	users int
+}
+
+func NewHostLimiter(maxConcurrent int, minDelay time.Duration) *HostLimiter {
+	return &HostLimiter{
+		MaxConcurrent: maxConcurrent,
+		MinDelay:      minDelay,
+		hosts:         make(map[string]*hostState),
+	}
+}
+
+// Acquire blocks until a request to host may start, or ctx is done. The
+// returned release function must be called once the request has finished.
+func (l *HostLimiter) Acquire(ctx context.Context, host string) (func(), error) {
+	host = strings.ToLower(host)
+
+	l.mu.Lock()
+	state, ok := l.hosts[host]
+	if !ok {
+		state = &hostState{}
+		if l.MaxConcurrent > 0 {
+			state.slots = make(chan struct{}, l.MaxConcurrent)
+		}
+		l.hosts[host] = state
+	}
+	state.users++
+	l.mu.Unlock()
+
+	if state.slots != nil {
+		select {
+		case state.slots <- struct{}{}:
+		case <-ctx.Done():
+			l.done(host, state)
+			return nil, ctx.Err()
+		}
+	}
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			if state.slots != nil {
+				<-state.slots
+			}
+			l.done(host, state)
+		})
+	}
+
+	// Reserve the next start time for this request before waiting for it
+	l.mu.Lock()
+	start := time.Now()
+	if state.nextStart.After(start) {
+		start = state.nextStart
+	}
+	state.nextStart = start.Add(l.MinDelay)
+	l.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// done records that a request to host no longer holds or waits for a slot,
+// and forgets the host once it is idle and its MinDelay has passed.
+func (l *HostLimiter) done(host string, state *hostState) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	state.users--
+	if state.users > 0 {
+		return
+	}
+	if wait := time.Until(state.nextStart); wait > 0 {
+		time.AfterFunc(wait, func() { l.forget(host, state) })
+		return
+	}
+	l.forgetLocked(host, state)
+}
+
+// forget drops host if state is still its entry, nobody uses it and the next
+// request would not have to wait for it.
+func (l *HostLimiter) forget(host string, state *hostState) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.forgetLocked(host, state)
+}
+
+// forgetLocked is forget with l.mu held.
+func (l *HostLimiter) forgetLocked(host string, state *hostState) {
+	if l.hosts[host] == state && state.users == 0 && !time.Now().Before(state.nextStart) {
+		delete(l.hosts, host)
+	}
+}
+
+// releasingBody releases a host slot when the response body is closed, so a
+// slot is held for as long as the connection is being read.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
+
+// doRequest sends req through the HTTP client once the host limiter allows
+// it. Waiting for the host does not count against the client's timeout.
+func (fs *FeedService) doRequest(req *http.Request) (*http.Response, error) {
+	release, err := fs.HostLimiter.Acquire(req.Context(), req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := fs.HTTPClient.Do(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// HTTPStatusError is returned when a fetch gets an unexpected HTTP status.
+// RetryAfter is set when a 429 or 503 response said how long to wait.
+type HTTPStatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("feed returned status %d", e.StatusCode)
+}
+
+func newHTTPStatusError(resp *http.Response) *HTTPStatusError {
+	err := &HTTPStatusError{StatusCode: resp.StatusCode}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		err.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+	return err
+}
+
+// maxRetryAfter bounds a Retry-After delay, whatever the server asks for.
+const maxRetryAfter = 7 * 24 * time.Hour
+
+// parseRetryAfter reads a Retry-After header, given either in seconds or as
+// an HTTP date. It returns zero when the header is missing or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(min(seconds, int(maxRetryAfter/time.Second))) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return min(date.Sub(now), maxRetryAfter)
+	}
+	return 0
+}
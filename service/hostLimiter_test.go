@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Rach17/Go-RSS-Aggregator/data"
+	"github.com/google/uuid"
+)
+
+// newImageFeedServer serves a feed whose image is on the same host, and a
+// home page that links to the feed.
+func newImageFeedServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0"><channel>
+<title>Image feed</title><link>http://` + r.Host + `/</link>
+<image><url>http://` + r.Host + `/logo.png</url><title>Logo</title></image>
+<item><title>Post</title><guid>1</guid></item>
+</channel></rss>`))
+	})
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><link rel="alternate" type="application/rss+xml" href="/feed"></head></html>`))
+	})
+	mux.HandleFunc("/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG\r\n\x1a\n"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFeedWithImageDoesNotHoldHostSlot(t *testing.T) {
+	server := newImageFeedServer(t)
+	fs, feedRepo, _ := newTestFeedService()
+	fs.HostLimiter = NewHostLimiter(1, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	feed, err := fs.CreateFeed(ctx, server.URL+"/feed", uuid.NullUUID{}, data.FeedCredentials{})
+	if err != nil {
+		t.Fatalf("CreateFeed: %v", err)
+	}
+	if _, err := feedRepo.GetFeedImage(ctx, feed.ID); err != nil {
+		t.Fatalf("image not cached on create: %v", err)
+	}
+
+	// Force the image to be fetched again on refresh
+	delete(feedRepo.images, feed.ID)
+	if err := fs.RefreshFeed(ctx, feed); err != nil {
+		t.Fatalf("RefreshFeed: %v", err)
+	}
+	if _, err := feedRepo.GetFeedImage(ctx, feed.ID); err != nil {
+		t.Fatalf("image not cached on refresh: %v", err)
+	}
+}
+
+func TestDiscoveryDoesNotHoldHostSlot(t *testing.T) {
+	server := newImageFeedServer(t)
+	fs, _, _ := newTestFeedService()
+	fs.HostLimiter = NewHostLimiter(1, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, feedURL, err := fs.ValidateAndFetchNewFeed(ctx, server.URL+"/")
+	if err != nil {
+		t.Fatalf("ValidateAndFetchNewFeed: %v", err)
+	}
+	if feedURL != server.URL+"/feed" {
+		t.Errorf("discovered %s, want %s/feed", feedURL, server.URL)
+	}
+}
+
+func TestHostLimiterBoundsConcurrency(t *testing.T) {
+	limiter := NewHostLimiter(1, 0)
+	ctx := context.Background()
+
+	release, err := limiter.Acquire(ctx, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second request to the same host waits for the first
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(waitCtx, "EXAMPLE.com"); err == nil {
+		t.Fatal("second Acquire on a full host succeeded")
+	}
+
+	// Other hosts are not affected
+	otherRelease, err := limiter.Acquire(ctx, "example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherRelease()
+
+	release()
+	release, err = limiter.Acquire(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("Acquire after release: %v", err)
+	}
+	release()
+}
+
+// trackedHosts returns the number of hosts the limiter remembers.
+func (l *HostLimiter) trackedHosts() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.hosts)
+}
+
+func TestHostLimiterForgetsIdleHosts(t *testing.T) {
+	ctx := context.Background()
+
+	limiter := NewHostLimiter(1, 0)
+	release, err := limiter.Acquire(ctx, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(waitCtx, "example.com"); err == nil {
+		t.Fatal("second Acquire on a full host succeeded")
+	}
+	if n := limiter.trackedHosts(); n != 1 {
+		t.Fatalf("%d hosts tracked while a request is in flight, want 1", n)
+	}
+	release()
+	if n := limiter.trackedHosts(); n != 0 {
+		t.Errorf("%d hosts tracked after the last release, want 0", n)
+	}
+
+	// A host is kept until its delay has passed, so the next request still waits
+	limiter = NewHostLimiter(1, 50*time.Millisecond)
+	release, err = limiter.Acquire(ctx, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if n := limiter.trackedHosts(); n != 1 {
+		t.Fatalf("%d hosts tracked before the delay passed, want 1", n)
+	}
+	start := time.Now()
+	release, err = limiter.Acquire(ctx, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 40*time.Millisecond {
+		t.Errorf("second request started after %v, want the delay", waited)
+	}
+	release()
+	deadline := time.Now().Add(time.Second)
+	for limiter.trackedHosts() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("host still tracked after its delay passed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{" 5 ", 5 * time.Second},
+		{"0", 0},
+		{"-3", 0},
+		{"soon", 0},
+		{"Mon, 01 Jan 2024 12:10:00 GMT", 10 * time.Minute},
+		{"Mon, 01 Jan 2024 11:00:00 GMT", 0},
+		{"99999999", maxRetryAfter},
+		{"Mon, 01 Jan 2035 12:00:00 GMT", maxRetryAfter},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrDestinationNotAllowed is returned when the network policy refuses to
+// connect to a host, address or port.
+var ErrDestinationNotAllowed = errors.New("destination not allowed")
+
+// reservedPrefixes are ranges that are not reachable on the public internet
+// but are not covered by the netip.Addr predicates.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// NetworkPolicy decides which destinations the fetcher may connect to. Feed
+// URLs come from users, so without it anyone could make the server reach
+// internal services. Addresses are checked at connect time, after DNS
+// resolution and on every redirect, so a hostname cannot be used to sneak
+// past it.
+//
+// Loopback, private, link-local, multicast and other reserved addresses are
+// refused unless the host or address is on the allow list. Anything on the
+// deny list is refused, even if it is also allowed. When allowed ports are
+// given only those ports may be used.
+type NetworkPolicy struct {
+	allowHosts hostList
+	denyHosts  hostList
+	allowPorts map[int]bool
+	denyPorts  map[int]bool
+}
+
+// hostList matches host names, including their subdomains, and addresses.
+type hostList struct {
+	names    []string
+	prefixes []netip.Prefix
+}
+
+// NewNetworkPolicy builds a policy from comma-separated lists. Hosts may be
+// names, IP addresses or CIDR ranges; ports are numbers.
+func NewNetworkPolicy(allowHosts, denyHosts, allowPorts, denyPorts string) (*NetworkPolicy, error) {
+	var policy NetworkPolicy
+	var err error
+	if policy.allowHosts, err = parseHostList(allowHosts); err != nil {
+		return nil, fmt.Errorf("invalid allowed hosts: %w", err)
+	}
+	if policy.denyHosts, err = parseHostList(denyHosts); err != nil {
+		return nil, fmt.Errorf("invalid denied hosts: %w", err)
+	}
+	if policy.allowPorts, err = parsePortList(allowPorts); err != nil {
+		return nil, fmt.Errorf("invalid allowed ports: %w", err)
+	}
+	if policy.denyPorts, err = parsePortList(denyPorts); err != nil {
+		return nil, fmt.Errorf("invalid denied ports: %w", err)
+	}
+	return &policy, nil
+}
+
+func parseHostList(list string) (hostList, error) {
+	var hosts hostList
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return hostList{}, err
+			}
+			hosts.prefixes = append(hosts.prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(strings.Trim(entry, "[]")); err == nil {
+			addr = addr.Unmap()
+			hosts.prefixes = append(hosts.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		} else {
+			hosts.names = append(hosts.names, strings.TrimSuffix(entry, "."))
+		}
+	}
+	return hosts, nil
+}
+
+func parsePortList(list string) (map[int]bool, error) {
+	ports := make(map[int]bool)
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		port, err := strconv.Atoi(entry)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q", entry)
+		}
+		ports[port] = true
+	}
+	return ports, nil
+}
+
+func (l hostList) matchesName(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, name := range l.names {
+		if host == name || strings.HasSuffix(host, "."+name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l hostList) matchesAddr(addr netip.Addr) bool {
+	for _, prefix := range l.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckURL rejects a URL whose host or port the policy refuses. Host names
+// are only fully checked once they are resolved, when connecting.
+func (p *NetworkPolicy) CheckURL(u *url.URL) error {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	if err := p.checkHost(u.Hostname(), port); err != nil {
+		return err
+	}
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil {
+		return p.checkAddr(addr, p.allowHosts.matchesName(u.Hostname()))
+	}
+	return nil
+}
+
+// checkHost applies the host name and port lists.
+func (p *NetworkPolicy) checkHost(host, port string) error {
+	if p.denyHosts.matchesName(host) {
+		return fmt.Errorf("%w: host %s is denied", ErrDestinationNotAllowed, host)
+	}
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		return fmt.Errorf("%w: invalid port %q", ErrDestinationNotAllowed, port)
+	}
+	if p.denyPorts[portNumber] || (len(p.allowPorts) > 0 && !p.allowPorts[portNumber]) {
+		return fmt.Errorf("%w: port %d is not allowed", ErrDestinationNotAllowed, portNumber)
+	}
+	return nil
+}
+
+// checkAddr applies the address lists and refuses non-public addresses,
+// unless the address or, when hostAllowed is set, its host name is allowed.
+func (p *NetworkPolicy) checkAddr(addr netip.Addr, hostAllowed bool) error {
+	addr = addr.Unmap().WithZone("")
+	if p.denyHosts.matchesAddr(addr) {
+		return fmt.Errorf("%w: address %s is denied", ErrDestinationNotAllowed, addr)
+	}
+	if hostAllowed || p.allowHosts.matchesAddr(addr) || isPublicAddr(addr) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is not a public address", ErrDestinationNotAllowed, addr)
+}
+
+func isPublicAddr(addr netip.Addr) bool {
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// DialContext connects like net.Dialer, checking the host and port before
+// resolving and every resolved address right before connecting to it.
+func (p *NetworkPolicy) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkHost(host, port); err != nil {
+		return nil, err
+	}
+	hostAllowed := p.allowHosts.matchesName(host)
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrDestinationNotAllowed, err)
+			}
+			return p.checkAddr(addrPort.Addr(), hostAllowed)
+		},
+	}
+	return dialer.DialContext(ctx, network, address)
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+func TestNetworkPolicyCheckURL(t *testing.T) {
+	policy, err := NewNetworkPolicy("intranet.example, 10.1.0.0/16", "blocked.example, 203.0.113.7", "", "25")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://example.com/feed", true},
+		{"http://93.184.216.34/feed", true},
+		{"http://127.0.0.1/feed", false},
+		{"http://[::1]/feed", false},
+		{"http://10.0.0.1/feed", false},
+		{"http://10.1.2.3/feed", true},
+		{"http://172.16.0.1/feed", false},
+		{"http://192.168.1.1/feed", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://100.64.0.1/feed", false},
+		{"http://0.0.0.0/feed", false},
+		{"http://[::ffff:127.0.0.1]/feed", false},
+		{"http://[fe80::1]/feed", false},
+		{"http://[fc00::1]/feed", false},
+		{"http://203.0.113.7/feed", false},
+		{"https://blocked.example/feed", false},
+		{"https://www.blocked.example/feed", false},
+		{"https://notblocked.example/feed", true},
+		{"https://intranet.example/feed", true},
+		{"https://example.com:25/feed", false},
+		{"https://example.com:8443/feed", true},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		err := policy.CheckURL(u)
+		if got := err == nil; got != tt.want {
+			t.Errorf("CheckURL(%s) allowed = %v, want %v (%v)", tt.url, got, tt.want, err)
+		}
+		if err != nil && !errors.Is(err, ErrDestinationNotAllowed) {
+			t.Errorf("CheckURL(%s) = %v, want ErrDestinationNotAllowed", tt.url, err)
+		}
+	}
+}
+
+func TestNetworkPolicyAllowedPorts(t *testing.T) {
+	policy, err := NewNetworkPolicy("", "", "80, 443", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rawURL, want := range map[string]bool{
+		"http://example.com/":       true,
+		"https://example.com/":      true,
+		"http://example.com:443/":   true,
+		"http://example.com:8080/":  false,
+		"https://example.com:6379/": false,
+	} {
+		u, _ := url.Parse(rawURL)
+		if got := policy.CheckURL(u) == nil; got != want {
+			t.Errorf("CheckURL(%s) allowed = %v, want %v", rawURL, got, want)
+		}
+	}
+}
+
+func TestNewNetworkPolicyRejectsInvalidLists(t *testing.T) {
+	tests := [][4]string{
+		{"10.0.0.0/33", "", "", ""},
+		{"", "::1/200", "", ""},
+		{"", "", "http", ""},
+		{"", "", "", "70000"},
+	}
+	for _, tt := range tests {
+		if _, err := NewNetworkPolicy(tt[0], tt[1], tt[2], tt[3]); err == nil {
+			t.Errorf("NewNetworkPolicy(%q) succeeded", tt)
+		}
+	}
+}
+
+func TestNetworkPolicyCheckAddr(t *testing.T) {
+	policy, _ := NewNetworkPolicy("", "", "", "")
+	for addr, want := range map[string]bool{
+		"8.8.8.8":           true,
+		"2001:4860::8888":   true,
+		"127.0.0.53":        false,
+		"::":                false,
+		"224.0.0.1":         false,
+		"198.18.0.1":        false,
+		"64:ff9b::a00:1":    false,
+		"::ffff:10.0.0.1":   false,
+		"fe80::1%eth0":      false,
+		"240.0.0.1":         false,
+		"192.0.0.8":         false,
+		"100.127.255.255":   false,
+		"100.128.0.1":       true,
+		"::ffff:8.8.4.4":    true,
+		"2606:4700::6810:1": true,
+	} {
+		if got := policy.checkAddr(netip.MustParseAddr(addr), false) == nil; got != want {
+			t.Errorf("checkAddr(%s) allowed = %v, want %v", addr, got, want)
+		}
+	}
+	// An allowed host name lets its private addresses through
+	if err := policy.checkAddr(netip.MustParseAddr("10.0.0.1"), true); err != nil {
+		t.Errorf("checkAddr with an allowed host: %v", err)
+	}
+}
+
+func TestFetchRefusesLoopbackByDefault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("connected to a loopback server")
+	}))
+	defer server.Close()
+
+	fs := NewFeedService(nil, nil)
+	_, err := fs.sendRequest(context.Background(), server.URL+"/feed", cacheValidators{})
+	if !errors.Is(err, ErrDestinationNotAllowed) {
+		t.Errorf("got %v, want ErrDestinationNotAllowed", err)
+	}
+}
+
+func TestRedirectToPrivateAddressRefused(t *testing.T) {
+	// The origin is allowed by name; the loopback address it redirects to is
+	// not, and is refused when the redirect is followed
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("followed a redirect to a loopback address")
+	}))
+	defer internal.Close()
+
+	mux := http.NewServeMux()
+	mux.Handle("/feed", http.RedirectHandler(internal.URL+"/", http.StatusFound))
+	origin := httptest.NewServer(mux)
+	defer origin.Close()
+	originURL, _ := url.Parse(origin.URL)
+
+	fs, _, _ := newTestFeedService()
+	fs.NetworkPolicy, _ = NewNetworkPolicy("localhost", "", "", "")
+	_, err := fs.sendRequest(context.Background(), "http://localhost:"+originURL.Port()+"/feed", cacheValidators{})
+	if !errors.Is(err, ErrDestinationNotAllowed) {
+		t.Errorf("got %v, want ErrDestinationNotAllowed", err)
+	}
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// robotsProductToken is the name our rules are looked up under in robots.txt.
+const robotsProductToken = "RSS-Aggregator"
+
+// robotsMaxBytes is how much of a robots.txt file is read; RFC 9309 asks
+// crawlers to parse at least the first 500 KiB.
+const robotsMaxBytes = 500 << 10
+
+// robotsCacheSize bounds the number of sites whose robots.txt is cached.
+const robotsCacheSize = 10000
+
+// robotsErrorTTL is how long a failed robots.txt lookup is remembered, so a
+// site whose robots.txt is down is not asked again by every fetch meanwhile.
+const robotsErrorTTL = 5 * time.Minute
+
+// robotsFetchTimeout bounds a robots.txt download. The download is shared by
+// every caller waiting for it, so it is not cancelled with any one of them.
+const robotsFetchTimeout = 30 * time.Second
+
+// ErrBlockedByRobots is returned when a site's robots.txt does not allow us
+// to fetch a URL.
+var ErrBlockedByRobots = errors.New("blocked by robots.txt")
+
+type robotsRule struct {
+	allow   bool
+	length  int
+	pattern *regexp.Regexp
+}
+
+// robotsEntry is the cached outcome of a robots.txt lookup: the rules, or
+// the error the lookup failed with.
+type robotsEntry struct {
+	rules     []robotsRule
+	err       error
+	expiresAt time.Time
+}
+
+// robotsLookup is a robots.txt download in progress. Lookups of the same site
+// wait for it instead of sending their own request.
+type robotsLookup struct {
+	done  chan struct{}
+	rules []robotsRule
+	err   error
+}
+
+type robotsRequestContextKey struct{}
+
+// isRobotsRequest reports whether ctx belongs to a robots.txt request, whose
+// redirects are not themselves checked against robots.txt.
+func isRobotsRequest(ctx context.Context) bool {
+	return ctx.Value(robotsRequestContextKey{}) != nil
+}
+
+// checkRobots returns an error wrapping ErrBlockedByRobots if the robots.txt
+// of target's site disallows it for our user agent. Rules are cached per
+// site for RobotsTTL. A missing robots.txt allows everything.
+func (fs *FeedService) checkRobots(ctx context.Context, target *url.URL) error {
+	rules, err := fs.robotsRules(ctx, target.Scheme+"://"+strings.ToLower(target.Host))
+	if err != nil {
+		return err
+	}
+
+	path := target.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if target.RawQuery != "" {
+		path += "?" + target.RawQuery
+	}
+	if !robotsAllowed(rules, path) {
+		return fmt.Errorf("%w: %s", ErrBlockedByRobots, target)
+	}
+	return nil
+}
+
+// robotsRules returns the cached rules of site, downloading its robots.txt
+// when they are missing or expired. Concurrent lookups of a site share one
+// download, which outlives any caller that gives up on it; a failed lookup
+// is remembered for robotsErrorTTL.
+func (fs *FeedService) robotsRules(ctx context.Context, site string) ([]robotsRule, error) {
+	fs.robotsMu.Lock()
+	if entry, ok := fs.robots[site]; ok && time.Now().Before(entry.expiresAt) {
+		fs.robotsMu.Unlock()
+		return entry.rules, entry.err
+	}
+	lookup, ok := fs.robotsLookups[site]
+	if !ok {
+		lookup = &robotsLookup{done: make(chan struct{})}
+		if fs.robotsLookups == nil {
+			fs.robotsLookups = make(map[string]*robotsLookup)
+		}
+		fs.robotsLookups[site] = lookup
+		go fs.lookupRobots(context.WithoutCancel(ctx), site, lookup)
+	}
+	fs.robotsMu.Unlock()
+
+	select {
+	case <-lookup.done:
+		return lookup.rules, lookup.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// lookupRobots downloads the robots.txt of site for everyone waiting on
+// lookup and caches the outcome.
+func (fs *FeedService) lookupRobots(ctx context.Context, site string, lookup *robotsLookup) {
+	ctx, cancel := context.WithTimeout(ctx, robotsFetchTimeout)
+	defer cancel()
+	lookup.rules, lookup.err = fs.fetchRobots(ctx, site)
+
+	ttl := fs.RobotsTTL
+	if lookup.err != nil {
+		ttl = robotsErrorTTL
+	}
+	fs.robotsMu.Lock()
+	delete(fs.robotsLookups, site)
+	fs.cacheRobots(site, robotsEntry{rules: lookup.rules, err: lookup.err, expiresAt: time.Now().Add(ttl)})
+	fs.robotsMu.Unlock()
+	close(lookup.done)
+}
+
+// cacheRobots stores the rules of a site. When the cache is full, expired
+// entries are dropped first, then the one closest to expiring. robotsMu must
+// be held.
+func (fs *FeedService) cacheRobots(site string, entry robotsEntry) {
+	if fs.robots == nil {
+		fs.robots = make(map[string]robotsEntry)
+	}
+	if _, ok := fs.robots[site]; !ok && len(fs.robots) >= robotsCacheSize {
+		now := time.Now()
+		oldest := ""
+		for cached, e := range fs.robots {
+			if now.After(e.expiresAt) {
+				delete(fs.robots, cached)
+			} else if oldest == "" || e.expiresAt.Before(fs.robots[oldest].expiresAt) {
+				oldest = cached
+			}
+		}
+		if len(fs.robots) >= robotsCacheSize {
+			delete(fs.robots, oldest)
+		}
+	}
+	fs.robots[site] = entry
+}
+
+// fetchRobots downloads and parses the robots.txt of site. Following RFC
+// 9309, a 4xx response means there are no rules, while a server error is
+// returned so the fetch is retried later rather than assumed allowed.
+//
+// The request bypasses the HostLimiter: it may be made from checkRedirect
+// while the request being redirected holds the slot of the same host, and
+// the cache keeps it to one request per site per RobotsTTL.
+func (fs *FeedService) fetchRobots(ctx context.Context, site string) ([]robotsRule, error) {
+	ctx = context.WithValue(ctx, robotsRequestContextKey{}, true)
+	req, err := http.NewRequestWithContext(ctx, "GET", site+"/robots.txt", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create robots.txt request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := fs.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch robots.txt: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		body, err := io.ReadAll(io.LimitReader(resp.Body, robotsMaxBytes))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read robots.txt: %w", err)
+		}
+		return parseRobots(body, robotsProductToken), nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("robots.txt returned status %d", resp.StatusCode)
+	}
+}
+
+// parseRobots returns the rules of the groups that name product, or of the
+// "*" groups if none does.
+func parseRobots(body []byte, product string) []robotsRule {
+	var specific, wildcard []robotsRule
+	var hasSpecific, matchesSpecific, matchesWildcard, inRules bool
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// A user-agent line after rules starts a new group
+			if inRules {
+				matchesSpecific, matchesWildcard, inRules = false, false, false
+			}
+			agent, _, _ := strings.Cut(value, "/")
+			if agent == "*" {
+				matchesWildcard = true
+			} else if strings.EqualFold(agent, product) {
+				matchesSpecific, hasSpecific = true, true
+			}
+		case "allow", "disallow":
+			inRules = true
+			// An empty disallow allows everything, which is the default anyway
+			if value == "" {
+				continue
+			}
+			rule, err := newRobotsRule(key == "allow", value)
+			if err != nil {
+				log.Printf("Ignoring robots.txt rule %q: %v", value, err)
+				continue
+			}
+			if matchesSpecific {
+				specific = append(specific, rule)
+			}
+			if matchesWildcard {
+				wildcard = append(wildcard, rule)
+			}
+		}
+	}
+
+	if hasSpecific {
+		return specific
+	}
+	return wildcard
+}
+
+// newRobotsRule compiles a path pattern, in which * matches any sequence of
+// characters and a trailing $ anchors the end of the path.
+func newRobotsRule(allow bool, pattern string) (robotsRule, error) {
+	anchored := strings.HasSuffix(pattern, "$")
+	expr := regexp.QuoteMeta(strings.TrimSuffix(pattern, "$"))
+	expr = "^" + strings.ReplaceAll(expr, `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	compiled, err := regexp.Compile(expr)
+	if err != nil {
+		return robotsRule{}, err
+	}
+	return robotsRule{allow: allow, length: len(pattern), pattern: compiled}, nil
+}
+
+// robotsAllowed applies the most specific matching rule to path, that is the
+// one with the longest pattern. Allow wins a tie, and no match allows.
+func robotsAllowed(rules []robotsRule, path string) bool {
+	allowed, longest := true, -1
+	for _, rule := range rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if rule.length > longest || (rule.length == longest && rule.allow) {
+			allowed, longest = rule.allow, rule.length
+		}
+	}
+	return allowed
+}
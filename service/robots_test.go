@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRedirectCheckedAgainstTargetRobots(t *testing.T) {
+	blocked := http.NewServeMux()
+	blocked.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /\n"))
+	})
+	blocked.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("fetched a URL disallowed by robots.txt")
+	})
+	target := httptest.NewServer(blocked)
+	defer target.Close()
+
+	redirecting := http.NewServeMux()
+	redirecting.Handle("/feed", http.RedirectHandler(target.URL+"/feed", http.StatusFound))
+	origin := httptest.NewServer(redirecting)
+	defer origin.Close()
+
+	// Both servers are on 127.0.0.1, so with one slot per host the robots.txt
+	// of the target must not wait for the slot held by the redirected request
+	fs, _, _ := newTestFeedService()
+	fs.HostLimiter = NewHostLimiter(1, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := fs.sendRequest(ctx, origin.URL+"/feed", cacheValidators{})
+	if !errors.Is(err, ErrBlockedByRobots) {
+		t.Fatalf("got %v, want ErrBlockedByRobots", err)
+	}
+}
+
+func TestRobotsLookupsShareOneRequest(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	}))
+	defer server.Close()
+
+	fs, _, _ := newTestFeedService()
+	target, _ := url.Parse(server.URL + "/feed")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fs.checkRobots(context.Background(), target); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("robots.txt requested %d times, want 1", n)
+	}
+}
+
+func TestRobotsLookupOutlivesCancelledCaller(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	}))
+	defer server.Close()
+
+	fs, _, _ := newTestFeedService()
+	target, _ := url.Parse(server.URL + "/feed")
+
+	// The caller that starts the download gives up on it
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := fs.checkRobots(ctx, target); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the caller's deadline", err)
+	}
+	// Another caller still gets the rules from the same download
+	if err := fs.checkRobots(context.Background(), target); err != nil {
+		t.Fatalf("checkRobots: %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("robots.txt requested %d times, want 1", n)
+	}
+}
+
+func TestRobotsErrorIsCached(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	fs, _, _ := newTestFeedService()
+	target, _ := url.Parse(server.URL + "/feed")
+	for i := 0; i < 3; i++ {
+		if err := fs.checkRobots(context.Background(), target); err == nil {
+			t.Fatal("fetch allowed while robots.txt fails")
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("robots.txt requested %d times, want 1", n)
+	}
+
+	// The error expires well before rules would
+	fs.robotsMu.Lock()
+	entry := fs.robots[server.URL]
+	fs.robotsMu.Unlock()
+	if ttl := time.Until(entry.expiresAt); ttl > robotsErrorTTL {
+		t.Errorf("error cached for %v, want at most %v", ttl, robotsErrorTTL)
+	}
+}
+
+func TestRobotsCacheIsBounded(t *testing.T) {
+	fs := &FeedService{}
+	now := time.Now()
+	for i := 0; i < robotsCacheSize; i++ {
+		fs.cacheRobots(fmt.Sprintf("https://site%d.example", i), robotsEntry{expiresAt: now.Add(time.Hour + time.Duration(i)*time.Second)})
+	}
+	fs.cacheRobots("https://new.example", robotsEntry{expiresAt: now.Add(2 * time.Hour)})
+
+	if len(fs.robots) != robotsCacheSize {
+		t.Errorf("cache holds %d entries, want %d", len(fs.robots), robotsCacheSize)
+	}
+	if _, ok := fs.robots["https://site0.example"]; ok {
+		t.Error("entry closest to expiring was kept")
+	}
+	if _, ok := fs.robots["https://new.example"]; !ok {
+		t.Error("new entry not cached")
+	}
+
+	// Expired entries make room before live ones are evicted
+	expired := fs.robots["https://site1.example"]
+	expired.expiresAt = now.Add(-time.Minute)
+	fs.robots["https://site1.example"] = expired
+	fs.cacheRobots("https://newer.example", robotsEntry{expiresAt: now.Add(2 * time.Hour)})
+	if _, ok := fs.robots["https://site2.example"]; !ok {
+		t.Error("live entry evicted while an expired one was cached")
+	}
+	if _, ok := fs.robots["https://site1.example"]; ok {
+		t.Error("expired entry kept")
+	}
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	rules := parseRobots([]byte(`
+User-agent: *
+Disallow: /
+
+User-agent: RSS-Aggregator
+Disallow: /private
+Allow: /private/feed.xml$
+Disallow: /*.php
+`), robotsProductToken)
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/", true},
+		{"/feed.xml", true},
+		{"/private", false},
+		{"/private/posts", false},
+		{"/private/feed.xml", true},
+		{"/private/feed.xml?x=1", false},
+		{"/index.php", false},
+		{"/index.php5", false},
+	}
+	for _, tt := range tests {
+		if got := robotsAllowed(rules, tt.path); got != tt.want {
+			t.Errorf("robotsAllowed(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
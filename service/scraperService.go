@@ -2,17 +2,26 @@ package service
 
 import (
     "context"
+    "fmt"
     "log"
+    "os"
     "sync"
     "time"
 
     "github.com/Rach17/Go-RSS-Aggregator/db"
     "github.com/Rach17/Go-RSS-Aggregator/repository"
+    "github.com/google/uuid"
 )
 
 type ScraperService struct {
     FeedService      *FeedService
     FeedRepo         repository.FeedRepository
+    // InstanceID identifies this scraper in the leases it takes on feeds, so
+    // several scrapers can share one database without fetching a feed twice.
+    InstanceID       string
+    // LeaseDuration is how long a claimed feed stays reserved for this
+    // instance; if the instance dies, other instances pick the feed up after it.
+    LeaseDuration    time.Duration
     ticker           *time.Ticker
     stopChan         chan bool
     wg               sync.WaitGroup
@@ -23,16 +32,28 @@ func NewScraperService(feedService *FeedService, feedRepo repository.FeedReposit
     return &ScraperService{
         FeedService:   feedService,
         FeedRepo:      feedRepo,
+        InstanceID:    defaultInstanceID(),
+        LeaseDuration: 5 * time.Minute,
         stopChan:      make(chan bool),
         feedsToFetch:  feedsToFetch,
     }
 }
 
+// defaultInstanceID builds an ID that is unique per process, even for several
+// scrapers on one host.
+func defaultInstanceID() string {
+    hostname, err := os.Hostname()
+    if err != nil {
+        hostname = "scraper"
+    }
+    return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8])
+}
+
 func (s *ScraperService) Start(interval time.Duration) {
     s.ticker = time.NewTicker(interval)
     s.wg.Add(1)
     
-    log.Printf("Scraper started , interval: %v, feeds per cycle: %d", interval, s.feedsToFetch)
+    log.Printf("Scraper started , checking for due feeds every %v, feeds per batch: %d", interval, s.feedsToFetch)
 
     go func() {
         defer s.wg.Done()
@@ -50,22 +71,50 @@ func (s *ScraperService) Start(interval time.Duration) {
     }()
 }
 
+// scrapeFeeds fetches every feed that is due, in batches of feedsToFetch,
+// longest overdue first. Each batch is leased to this instance so other
+// instances skip it. A fetch reschedules its feed, so batches are taken until
+// no feed is due any more.
 func (s *ScraperService) scrapeFeeds() {
     ctx := context.Background()
-    
-    // Get the least recently fetched feeds
-    feeds, err := s.FeedRepo.GetLastFetchedFeeds(ctx, s.feedsToFetch)
-    if err != nil {
-        log.Printf("Error fetching feeds: %v", err)
-        return
+    scraped := make(map[uuid.UUID]bool)
+
+    for {
+        feeds, err := s.FeedRepo.ClaimDueFeeds(ctx, s.InstanceID, s.LeaseDuration, s.feedsToFetch)
+        if err != nil {
+            log.Printf("Error claiming due feeds: %v", err)
+            return
+        }
+
+        // A feed that could not be rescheduled stays due; never fetch it twice in a cycle
+        var batch []db.Feed
+        for _, feed := range feeds {
+            if !scraped[feed.ID] {
+                scraped[feed.ID] = true
+                batch = append(batch, feed)
+            } else {
+                s.releaseLease(ctx, feed)
+            }
+        }
+        if len(batch) == 0 {
+            break
+        }
+
+        s.scrapeBatch(batch)
+        if len(feeds) < s.feedsToFetch {
+            break
+        }
     }
 
-    if len(feeds) == 0 {
-        log.Println("No feeds found to scrape")
+    if len(scraped) == 0 {
+        log.Println("No feeds due for scraping")
         return
     }
+    log.Printf("Scrape cycle completed for %d feeds", len(scraped))
+}
 
-    log.Printf("Starting scrape cycle for %d feeds", len(feeds))
+func (s *ScraperService) scrapeBatch(feeds []db.Feed) {
+    log.Printf("Starting scrape batch for %d feeds", len(feeds))
     
     // Create a semaphore to limit concurrent goroutines
     semaphore := make(chan struct{}, s.feedsToFetch)
@@ -83,12 +132,12 @@ func (s *ScraperService) scrapeFeeds() {
             defer func() { <-semaphore }() // Release semaphore
             
             s.scrapeFeed(goroutineID, feedData)
+            s.releaseLease(context.Background(), feedData)
         }(i+1, feed)
     }
     
     // Wait for all goroutines to complete
     scrapeWg.Wait()
-    log.Printf("Scrape cycle completed for %d feeds", len(feeds))
 }
 
 func (s *ScraperService) scrapeFeed(goroutineID int, feed db.Feed) {
@@ -98,7 +147,7 @@ func (s *ScraperService) scrapeFeed(goroutineID int, feed db.Feed) {
     log.Printf("Goroutine %d: Starting scrape for feed: %s (URL: %s)", 
         goroutineID, feed.Title, feed.Url)
     
-    if err := s.FeedService.UpdateFeed(ctx, feed.Url); err != nil {
+    if err := s.FeedService.RefreshFeed(ctx, feed); err != nil {
         log.Printf("Goroutine %d: Error updating feed %s: %v", 
             goroutineID, feed.Title, err)
     } else {
@@ -108,6 +157,14 @@ func (s *ScraperService) scrapeFeed(goroutineID int, feed db.Feed) {
     }
 }
 
+// releaseLease hands a claimed feed back once this instance is done with it.
+// A failed release only delays other instances until the lease expires.
+func (s *ScraperService) releaseLease(ctx context.Context, feed db.Feed) {
+    if err := s.FeedRepo.ReleaseFeedLease(ctx, feed.ID, s.InstanceID); err != nil {
+        log.Printf("Error releasing lease on feed %s: %v", feed.Url, err)
+    }
+}
+
 func (s *ScraperService) Stop() {
     if s.ticker != nil {
         s.ticker.Stop()
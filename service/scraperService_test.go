@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// countingFeedServer serves the same small feed at every /feed path and
+// counts the requests made for each.
+type countingFeedServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func newCountingFeedServer(t *testing.T) *countingFeedServer {
+	t.Helper()
+	s := &countingFeedServer{requests: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/feed") {
+			http.NotFound(w, r)
+			return
+		}
+		s.mu.Lock()
+		s.requests[r.URL.Path]++
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<rss version="2.0"><channel><title>T</title><item><guid>1</guid></item></channel></rss>`))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *countingFeedServer) count(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func TestScrapeFeedsCoversEveryDueFeed(t *testing.T) {
+	server := newCountingFeedServer(t)
+	fs, feedRepo, _ := newTestFeedService()
+	ctx := context.Background()
+
+	var paths []string
+	for i := 0; i < 7; i++ {
+		path := "/feed" + string(rune('a'+i))
+		paths = append(paths, path)
+		feed, _ := feedRepo.CreateFeed(ctx, path, server.URL+path, "", "", "", "", uuid.NullUUID{}, nil)
+		feed.NextFetchAt = time.Now().Add(-time.Duration(i) * time.Minute)
+		feedRepo.feeds[feed.ID] = feed
+	}
+	// Not due yet
+	later, _ := feedRepo.CreateFeed(ctx, "later", server.URL+"/feed-later", "", "", "", "", uuid.NullUUID{}, nil)
+	later.NextFetchAt = time.Now().Add(time.Hour)
+	feedRepo.feeds[later.ID] = later
+
+	scraper := NewScraperService(fs, feedRepo, 2)
+	scraper.scrapeFeeds()
+
+	for _, path := range paths {
+		if n := server.count(path); n != 1 {
+			t.Errorf("%s fetched %d times, want 1", path, n)
+		}
+	}
+	if n := server.count("/feed-later"); n != 0 {
+		t.Errorf("feed that is not due fetched %d times", n)
+	}
+	for _, feed := range feedRepo.feeds {
+		if feed.ID != later.ID && !feed.NextFetchAt.After(time.Now()) {
+			t.Errorf("%s not rescheduled", feed.Url)
+		}
+	}
+}
+
+func TestScrapersShareFeedsThroughLeases(t *testing.T) {
+	server := newCountingFeedServer(t)
+	fs, feedRepo, _ := newTestFeedService()
+	ctx := context.Background()
+
+	var paths []string
+	for i := 0; i < 12; i++ {
+		path := "/feed" + string(rune('a'+i))
+		paths = append(paths, path)
+		feedRepo.CreateFeed(ctx, path, server.URL+path, "", "", "", "", uuid.NullUUID{}, nil)
+	}
+	// One feed is held by a live instance, another by one that crashed
+	held, _ := feedRepo.CreateFeed(ctx, "held", server.URL+"/feed-held", "", "", "", "", uuid.NullUUID{}, nil)
+	held.LeaseOwner = sql.NullString{String: "other", Valid: true}
+	held.LeaseExpiresAt = sql.NullTime{Time: time.Now().Add(time.Minute), Valid: true}
+	feedRepo.feeds[held.ID] = held
+	abandoned, _ := feedRepo.CreateFeed(ctx, "abandoned", server.URL+"/feed-abandoned", "", "", "", "", uuid.NullUUID{}, nil)
+	abandoned.LeaseOwner = sql.NullString{String: "crashed", Valid: true}
+	abandoned.LeaseExpiresAt = sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}
+	feedRepo.feeds[abandoned.ID] = abandoned
+	paths = append(paths, "/feed-abandoned")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		scraper := NewScraperService(fs, feedRepo, 2)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			scraper.scrapeFeeds()
+		}()
+	}
+	wg.Wait()
+
+	for _, path := range paths {
+		if n := server.count(path); n != 1 {
+			t.Errorf("%s fetched %d times, want 1", path, n)
+		}
+	}
+	if n := server.count("/feed-held"); n != 0 {
+		t.Errorf("feed leased to another instance fetched %d times", n)
+	}
+	for _, feed := range feedRepo.feeds {
+		if feed.ID != held.ID && feed.LeaseOwner.Valid {
+			t.Errorf("lease on %s not released", feed.Url)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+-- name: CreateFeedAlias :exec
+insert into feed_aliases (url, feed_id)
+values ($1, $2)
+on conflict (url) do update set feed_id = excluded.feed_id;
+
+-- name: DeleteFeedAlias :exec
+delete from feed_aliases
+where url = $1;
+
+-- name: MoveFeedAliases :exec
+update feed_aliases
+set feed_id = sqlc.arg(to_feed_id)
+where feed_id = sqlc.arg(from_feed_id);
@@ -0,0 +1,12 @@
+-- name: UpsertFeedCredentials :exec
+INSERT INTO feed_credentials (feed_id, encrypted)
+VALUES ($1, $2)
+ON CONFLICT (feed_id) DO UPDATE
+SET encrypted = EXCLUDED.encrypted,
+    updated_at = NOW();
+
+-- name: GetFeedCredentials :one
+SELECT * FROM feed_credentials WHERE feed_id = $1;
+
+-- name: DeleteFeedCredentials :exec
+DELETE FROM feed_credentials WHERE feed_id = $1;
@@ -0,0 +1,13 @@
+-- name: CreateFeedEvent :exec
+insert into feed_events (feed_id, event_type, old_value, new_value)
+values ($1, $2, $3, $4);
+
+-- name: GetFeedEventsByFeedID :many
+select * from feed_events
+where feed_id = $1
+order by created_at desc;
+
+-- name: MoveFeedEvents :exec
+update feed_events
+set feed_id = sqlc.arg(to_feed_id)
+where feed_id = sqlc.arg(from_feed_id);
@@ -0,0 +1,11 @@
+-- name: UpsertFeedImage :exec
+INSERT INTO feed_images (feed_id, source_url, content_type, data)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (feed_id) DO UPDATE
+SET source_url = EXCLUDED.source_url,
+    content_type = EXCLUDED.content_type,
+    data = EXCLUDED.data,
+    updated_at = NOW();
+
+-- name: GetFeedImage :one
+SELECT * FROM feed_images WHERE feed_id = $1;
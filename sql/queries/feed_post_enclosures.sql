@@ -0,0 +1,9 @@
+-- name: CreateFeedPostEnclosure :exec
+insert into feed_post_enclosures (feed_post_id, url, mime_type, length)
+values ($1, $2, $3, $4)
+on conflict (feed_post_id, url) do nothing;
+
+-- name: GetFeedPostEnclosuresByFeedID :many
+select feed_post_enclosures.* from feed_post_enclosures
+join feed_posts on feed_posts.id = feed_post_enclosures.feed_post_id
+where feed_posts.feed_id = $1;
@@ -1,12 +1,43 @@
--- name: CreateFeedPost :exec
--- description: Create a new feed post
-insert into feed_posts (feed_id, title, url, description,author, published_at)
-values ($1, $2, $3, $4, $5, $6);
+-- name: CreateFeedPost :one
+-- description: Create a new feed post, skipping items the feed already has
+insert into feed_posts (feed_id, title, url, description,author, published_at, guid, content, description_raw, content_raw,
+    itunes_duration_seconds, itunes_image, itunes_episode, itunes_season, itunes_explicit, thumbnail_url, published_at_source, base_url)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+on conflict (feed_id, guid) do nothing
+returning id;
 
 -- name: GetFeedPosts :many
 select sqlc.embed(feeds), sqlc.embed(feed_posts) from feed_posts, feeds
-where feed_posts.url = $1 and feed_posts.feed_id = feeds.id;
+where feeds.id = $1 and feed_posts.feed_id = feeds.id
+order by feed_posts.published_at desc;
 
--- name: GetFeedPostsUrlAndTitle :many
-select url, title from feed_posts
-where feed_id = $1;
\ No newline at end of file
+-- name: GetRecentFeedPostTimes :many
+-- description: Get the publication times of a feed's latest dated posts
+select published_at from feed_posts
+where feed_id = $1 and published_at_source = 'item'
+order by published_at desc
+limit $2;
+
+-- name: GetFeedPostGuids :many
+select guid from feed_posts
+where feed_id = $1;
+
+
+-- name: GetFeedPostsRawContent :many
+-- description: Get a page of the raw post content, ordered by ID and starting after after_id
+select id, url, base_url, description_raw, content_raw from feed_posts
+where id > sqlc.arg(after_id)
+order by id
+limit sqlc.arg(page_size);
+
+-- name: UpdateFeedPostSanitizedContent :exec
+update feed_posts
+set description = $2, content = $3
+where id = $1;
+
+-- name: MoveFeedPosts :exec
+-- description: Move the posts of one feed to another, leaving behind those the target already has
+update feed_posts
+set feed_id = sqlc.arg(to_feed_id)
+where feed_posts.feed_id = sqlc.arg(from_feed_id)
+  and feed_posts.guid not in (select existing.guid from feed_posts existing where existing.feed_id = sqlc.arg(to_feed_id));
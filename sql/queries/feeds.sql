@@ -1,21 +1,72 @@
 -- name: CreateFeed :one
-INSERT INTO feeds (title, url, description, language)
-VALUES ($1, $2, $3, $4)
+INSERT INTO feeds (title, url, description, language, site_url, image_url, owner_user_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING *;
 
 -- name: GetFeedByID :one
 SELECT * FROM feeds WHERE id = $1;
 
 -- name: GetFeedByURL :one
-SELECT * FROM feeds WHERE url = $1;
+-- description: Get a public feed by its URL or by a URL it has moved away from
+SELECT * FROM feeds
+WHERE owner_user_id IS NULL
+  AND (url = $1 OR id = (SELECT feed_id FROM feed_aliases WHERE feed_aliases.url = $1))
+ORDER BY url = $1 DESC
+LIMIT 1;
+
+-- name: GetVisibleFeedByURL :one
+-- description: Get the feed a user sees at a URL: their own private feed, else the public one
+SELECT * FROM feeds
+WHERE (url = sqlc.arg(url) AND owner_user_id = sqlc.arg(user_id))
+   OR (owner_user_id IS NULL
+       AND (url = sqlc.arg(url) OR id = (SELECT feed_id FROM feed_aliases WHERE feed_aliases.url = sqlc.arg(url))))
+ORDER BY owner_user_id IS NULL, url = sqlc.arg(url) DESC
+LIMIT 1;
 
 -- name: UpdateFeedLastFetchedAt :exec
 UPDATE feeds
 SET last_fetched_at = NOW()
-WHERE url = $1;
+WHERE id = $1;
+
+-- name: UpdateFeedCacheValidators :exec
+UPDATE feeds
+SET etag = $2, last_modified = $3
+WHERE id = $1;
+
+-- name: RecordFeedFetchSuccess :exec
+UPDATE feeds
+SET consecutive_errors = 0, last_error = NULL, last_success_at = NOW(), next_retry_at = NULL, fetch_status = 'ok',
+    next_fetch_at = $2, fetch_interval_seconds = $3, fetch_warning = $4
+WHERE id = $1;
+
+-- name: RecordFeedFetchFailure :exec
+UPDATE feeds
+SET consecutive_errors = $2, last_error = $3, next_retry_at = $4, fetch_status = $5, next_fetch_at = COALESCE($4, next_fetch_at)
+WHERE id = $1;
+
+-- name: UpdateFeedPollHints :exec
+UPDATE feeds
+SET ttl_minutes = $2, update_period_minutes = $3, skip_hours = $4, skip_days = $5
+WHERE id = $1;
+
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $2, description = $3, language = $4, site_url = $5, image_url = $6, updated_at = NOW()
+WHERE id = $1;
 
 -- name: GetAllFeeds :many
-SELECT * FROM feeds;
+-- description: Get the public feeds and the user's own private feeds
+SELECT * FROM feeds
+WHERE owner_user_id IS NULL OR owner_user_id = $1;
 
 
 -- name: FollowFeed :exec
@@ -23,7 +74,27 @@ INSERT INTO feed_follow (user_id, feed_id)
 VALUES ($1, $2)
 ON CONFLICT (user_id, feed_id) DO NOTHING;
 
--- name: GetLastFetchedFeeds :many
-SELECT * FROM feeds
-ORDER BY last_fetched_at DESC
-LIMIT $1;
+-- name: MoveFeedFollows :exec
+INSERT INTO feed_follow (user_id, feed_id)
+SELECT user_id, sqlc.arg(to_feed_id) FROM feed_follow
+WHERE feed_follow.feed_id = sqlc.arg(from_feed_id)
+ON CONFLICT (user_id, feed_id) DO NOTHING;
+
+-- name: ClaimDueFeeds :many
+-- description: Lease due feeds to a scraper instance, longest overdue first, skipping feeds another instance holds
+UPDATE feeds
+SET lease_owner = sqlc.arg(lease_owner), lease_expires_at = NOW() + sqlc.arg(lease_seconds)::integer * INTERVAL '1 second'
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE fetch_status <> 'disabled' AND next_fetch_at <= NOW()
+      AND (lease_expires_at IS NULL OR lease_expires_at <= NOW())
+    ORDER BY next_fetch_at
+    LIMIT sqlc.arg(max_feeds)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: ReleaseFeedLease :exec
+UPDATE feeds
+SET lease_owner = NULL, lease_expires_at = NULL
+WHERE id = $1 AND lease_owner = $2;
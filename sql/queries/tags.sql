@@ -0,0 +1,36 @@
+-- name: CreateFeedPostTag :exec
+insert into feed_post_tags (feed_post_id, tag_id)
+values ($1, $2)
+on conflict do nothing;
+
+-- name: GetFeedPostTagsByFeedID :many
+select feed_post_tags.feed_post_id, tags.name from feed_post_tags
+join tags on tags.id = feed_post_tags.tag_id
+join feed_posts on feed_posts.id = feed_post_tags.feed_post_id
+where feed_posts.feed_id = $1
+order by tags.name;
+
+-- name: GetFeedPostsByTag :many
+-- description: Get the posts with a tag, in one feed or in every feed the user can see
+select feed_posts.* from feed_posts
+join feed_post_tags on feed_post_tags.feed_post_id = feed_posts.id
+join tags on tags.id = feed_post_tags.tag_id
+join feeds on feeds.id = feed_posts.feed_id
+where tags.name = sqlc.arg(name)
+  and (sqlc.narg(feed_id)::uuid is null or feeds.id = sqlc.narg(feed_id))
+  and (feeds.owner_user_id is null or feeds.owner_user_id = sqlc.arg(user_id))
+order by feed_posts.published_at desc;
+
+-- name: GetFeedTags :many
+select tags.name, count(*) as post_count from tags
+join feed_post_tags on feed_post_tags.tag_id = tags.id
+join feed_posts on feed_posts.id = feed_post_tags.feed_post_id
+where feed_posts.feed_id = $1
+group by tags.name
+order by post_count desc, tags.name;
+
+-- name: UpsertTag :one
+insert into tags (name)
+values ($1)
+on conflict (name) do update set name = excluded.name
+returning id;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN etag text;
+ALTER TABLE feeds ADD COLUMN last_modified text;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN last_modified;
+ALTER TABLE feeds DROP COLUMN etag;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE feed_posts ADD COLUMN guid text;
+UPDATE feed_posts SET guid = url;
+ALTER TABLE feed_posts ALTER COLUMN guid SET NOT NULL;
+ALTER TABLE feed_posts DROP CONSTRAINT feed_posts_url_key;
+ALTER TABLE feed_posts ADD CONSTRAINT feed_posts_feed_id_guid_key UNIQUE (feed_id, guid);
+
+-- +goose Down
+ALTER TABLE feed_posts DROP CONSTRAINT feed_posts_feed_id_guid_key;
+ALTER TABLE feed_posts ADD CONSTRAINT feed_posts_url_key UNIQUE (url);
+ALTER TABLE feed_posts DROP COLUMN guid;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE feed_posts ADD COLUMN content text;
+
+-- +goose Down
+ALTER TABLE feed_posts DROP COLUMN content;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Existing rows only get a copy of what was stored: their description and
+-- content are not sanitised by this migration. Run the scraper once with
+-- SCRAPER_RESANITIZE=true after migrating, so they are sanitised from the
+-- raw columns like newly ingested posts.
+ALTER TABLE feed_posts ADD COLUMN description_raw text;
+ALTER TABLE feed_posts ADD COLUMN content_raw text;
+UPDATE feed_posts SET description_raw = description, content_raw = content;
+
+-- +goose Down
+ALTER TABLE feed_posts DROP COLUMN content_raw;
+ALTER TABLE feed_posts DROP COLUMN description_raw;
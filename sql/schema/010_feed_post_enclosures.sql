@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE feed_posts ADD COLUMN itunes_duration_seconds integer;
+ALTER TABLE feed_posts ADD COLUMN itunes_image text;
+ALTER TABLE feed_posts ADD COLUMN itunes_episode integer;
+ALTER TABLE feed_posts ADD COLUMN itunes_season integer;
+ALTER TABLE feed_posts ADD COLUMN itunes_explicit boolean;
+
+create table feed_post_enclosures (
+    id              uuid primary key default gen_random_uuid(),
+    created_at      timestamp with time zone default now() not null,
+    feed_post_id    uuid not null references feed_posts(id) on delete cascade,
+    url             text not null,
+    mime_type       text,
+    length          bigint,
+    unique (feed_post_id, url)
+);
+
+-- +goose Down
+drop table feed_post_enclosures;
+ALTER TABLE feed_posts DROP COLUMN itunes_explicit;
+ALTER TABLE feed_posts DROP COLUMN itunes_season;
+ALTER TABLE feed_posts DROP COLUMN itunes_episode;
+ALTER TABLE feed_posts DROP COLUMN itunes_image;
+ALTER TABLE feed_posts DROP COLUMN itunes_duration_seconds;
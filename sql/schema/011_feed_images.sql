@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN site_url text;
+ALTER TABLE feeds ADD COLUMN image_url text;
+ALTER TABLE feed_posts ADD COLUMN thumbnail_url text;
+
+create table feed_images (
+    feed_id         uuid primary key references feeds(id) on delete cascade,
+    created_at      timestamp with time zone default now() not null,
+    updated_at      timestamp with time zone default null,
+    source_url      text not null,
+    content_type    text not null,
+    data            bytea not null
+);
+
+-- +goose Down
+drop table feed_images;
+ALTER TABLE feed_posts DROP COLUMN thumbnail_url;
+ALTER TABLE feeds DROP COLUMN image_url;
+ALTER TABLE feeds DROP COLUMN site_url;
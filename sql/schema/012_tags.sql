@@ -0,0 +1,18 @@
+-- +goose Up
+create table tags (
+    id          uuid primary key default gen_random_uuid(),
+    created_at  timestamp with time zone default now() not null,
+    name        text not null unique
+);
+
+create table feed_post_tags (
+    feed_post_id    uuid not null references feed_posts(id) on delete cascade,
+    tag_id          uuid not null references tags(id) on delete cascade,
+    primary key (feed_post_id, tag_id)
+);
+
+create index feed_post_tags_tag_id_idx on feed_post_tags (tag_id);
+
+-- +goose Down
+drop table feed_post_tags;
+drop table tags;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE feed_posts ADD COLUMN published_at_source text;
+
+-- +goose Down
+ALTER TABLE feed_posts DROP COLUMN published_at_source;
@@ -0,0 +1,14 @@
+-- +goose Up
+create table feed_events (
+    id          uuid primary key default gen_random_uuid(),
+    created_at  timestamp with time zone default now() not null,
+    feed_id     uuid not null references feeds(id) on delete cascade,
+    event_type  text not null,
+    old_value   text,
+    new_value   text
+);
+
+create index feed_events_feed_id_created_at_idx on feed_events (feed_id, created_at);
+
+-- +goose Down
+drop table feed_events;
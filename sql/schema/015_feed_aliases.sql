@@ -0,0 +1,11 @@
+-- +goose Up
+create table feed_aliases (
+    url             text primary key,
+    created_at      timestamp with time zone default now() not null,
+    feed_id         uuid not null references feeds(id) on delete cascade
+);
+
+create index feed_aliases_feed_id_idx on feed_aliases (feed_id);
+
+-- +goose Down
+drop table feed_aliases;
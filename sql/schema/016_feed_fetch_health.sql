@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN consecutive_errors integer not null default 0;
+ALTER TABLE feeds ADD COLUMN last_error text;
+ALTER TABLE feeds ADD COLUMN last_success_at timestamp with time zone;
+ALTER TABLE feeds ADD COLUMN next_retry_at timestamp with time zone;
+ALTER TABLE feeds ADD COLUMN fetch_status text not null default 'ok'
+    check (fetch_status in ('ok', 'error', 'disabled'));
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN fetch_status;
+ALTER TABLE feeds DROP COLUMN next_retry_at;
+ALTER TABLE feeds DROP COLUMN last_success_at;
+ALTER TABLE feeds DROP COLUMN last_error;
+ALTER TABLE feeds DROP COLUMN consecutive_errors;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN next_fetch_at timestamp with time zone default now() not null;
+
+create index feeds_next_fetch_at_idx on feeds (next_fetch_at) where fetch_status <> 'disabled';
+
+-- +goose Down
+drop index feeds_next_fetch_at_idx;
+ALTER TABLE feeds DROP COLUMN next_fetch_at;
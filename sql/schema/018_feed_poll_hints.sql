@@ -0,0 +1,16 @@
+-- +goose Up
+-- Polling hints published by the feed: <ttl>, sy:updatePeriod/updateFrequency,
+-- and comma-separated <skipHours> hours (0-23, GMT) and <skipDays> day names
+ALTER TABLE feeds ADD COLUMN ttl_minutes integer;
+ALTER TABLE feeds ADD COLUMN update_period_minutes integer;
+ALTER TABLE feeds ADD COLUMN skip_hours text;
+ALTER TABLE feeds ADD COLUMN skip_days text;
+-- The poll interval computed after the last successful fetch
+ALTER TABLE feeds ADD COLUMN fetch_interval_seconds integer;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN fetch_interval_seconds;
+ALTER TABLE feeds DROP COLUMN skip_days;
+ALTER TABLE feeds DROP COLUMN skip_hours;
+ALTER TABLE feeds DROP COLUMN update_period_minutes;
+ALTER TABLE feeds DROP COLUMN ttl_minutes;
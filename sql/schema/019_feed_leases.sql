@@ -0,0 +1,9 @@
+-- +goose Up
+-- A scraper instance claims a feed before fetching it, so several instances
+-- can run side by side. A lease left behind by a crashed instance expires.
+ALTER TABLE feeds ADD COLUMN lease_owner text;
+ALTER TABLE feeds ADD COLUMN lease_expires_at timestamp with time zone;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN lease_expires_at;
+ALTER TABLE feeds DROP COLUMN lease_owner;
@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE feeds DROP CONSTRAINT feeds_fetch_status_check;
+ALTER TABLE feeds ADD CONSTRAINT feeds_fetch_status_check
+    check (fetch_status in ('ok', 'error', 'disabled', 'robots_blocked'));
+
+-- +goose Down
+UPDATE feeds SET fetch_status = 'error' WHERE fetch_status = 'robots_blocked';
+ALTER TABLE feeds DROP CONSTRAINT feeds_fetch_status_check;
+ALTER TABLE feeds ADD CONSTRAINT feeds_fetch_status_check
+    check (fetch_status in ('ok', 'error', 'disabled'));
@@ -0,0 +1,7 @@
+-- +goose Up
+-- A problem with the last successful fetch that did not make it fail, such
+-- as a document truncated at the size limits
+ALTER TABLE feeds ADD COLUMN fetch_warning text;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN fetch_warning;
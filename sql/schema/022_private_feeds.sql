@@ -0,0 +1,24 @@
+-- +goose Up
+-- A private feed is visible only to the user who added it. The same URL may
+-- be added privately by several users, next to a public feed for it.
+ALTER TABLE feeds ADD COLUMN owner_user_id uuid references users(id) on delete cascade;
+
+ALTER TABLE feeds DROP CONSTRAINT feeds_url_key;
+create unique index feeds_public_url_key on feeds (url) where owner_user_id is null;
+create unique index feeds_private_url_key on feeds (url, owner_user_id) where owner_user_id is not null;
+
+-- The credentials and headers a private feed is fetched with, encrypted
+create table feed_credentials (
+    feed_id         uuid primary key references feeds(id) on delete cascade,
+    created_at      timestamp with time zone default now() not null,
+    updated_at      timestamp with time zone default null,
+    encrypted       bytea not null
+);
+
+-- +goose Down
+drop table feed_credentials;
+DELETE FROM feeds WHERE owner_user_id IS NOT NULL;
+drop index feeds_private_url_key;
+drop index feeds_public_url_key;
+ALTER TABLE feeds ADD CONSTRAINT feeds_url_key UNIQUE (url);
+ALTER TABLE feeds DROP COLUMN owner_user_id;
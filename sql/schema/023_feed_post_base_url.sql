@@ -0,0 +1,9 @@
+-- +goose Up
+-- The URL the raw description and content were resolved against at ingest
+-- (the item's xml:base, or the feed URL), so they can be sanitised again with
+-- the same base. Rows stored before this have none and fall back to the
+-- post's URL.
+ALTER TABLE feed_posts ADD COLUMN base_url text;
+
+-- +goose Down
+ALTER TABLE feed_posts DROP COLUMN base_url;
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// windows1252High maps bytes 0x80-0x9F of windows-1252 to Unicode. The rest
+// of the code page is identical to ISO-8859-1.
+var windows1252High = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+// iso885915Diffs lists where ISO-8859-15 differs from ISO-8859-1.
+var iso885915Diffs = map[byte]rune{
+	0xA4: 0x20AC,
+	0xA6: 0x0160,
+	0xA8: 0x0161,
+	0xB4: 0x017D,
+	0xB8: 0x017E,
+	0xBC: 0x0152,
+	0xBD: 0x0153,
+	0xBE: 0x0178,
+}
+
+var windows1252Table, iso885915Table [256]rune
+
+func init() {
+	for i := range windows1252Table {
+		windows1252Table[i] = rune(i)
+		iso885915Table[i] = rune(i)
+	}
+	for i, r := range windows1252High {
+		windows1252Table[0x80+i] = r
+	}
+	for b, r := range iso885915Diffs {
+		iso885915Table[b] = r
+	}
+}
+
+// charsetTables maps normalised charset labels to their decoding tables. As
+// browsers do, ISO-8859-1 and US-ASCII are decoded as windows-1252, which is
+// a superset of both in practice.
+var charsetTables = map[string]*[256]rune{
+	"windows-1252": &windows1252Table,
+	"cp1252":       &windows1252Table,
+	"x-cp1252":     &windows1252Table,
+	"iso-8859-1":   &windows1252Table,
+	"iso8859-1":    &windows1252Table,
+	"iso_8859-1":   &windows1252Table,
+	"latin1":       &windows1252Table,
+	"l1":           &windows1252Table,
+	"cp819":        &windows1252Table,
+	"us-ascii":     &windows1252Table,
+	"ascii":        &windows1252Table,
+	"iso-8859-15":  &iso885915Table,
+	"iso8859-15":   &iso885915Table,
+	"iso_8859-15":  &iso885915Table,
+	"latin9":       &iso885915Table,
+	"latin-9":      &iso885915Table,
+}
+
+func normaliseCharset(label string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(label), `"'`))
+}
+
+// IsUTF8Charset reports whether label names UTF-8.
+func IsUTF8Charset(label string) bool {
+	switch normaliseCharset(label) {
+	case "utf-8", "utf8", "unicode-1-1-utf-8":
+		return true
+	}
+	return false
+}
+
+// IsSupportedCharset reports whether NewCharsetReader can decode label.
+func IsSupportedCharset(label string) bool {
+	_, ok := charsetTables[normaliseCharset(label)]
+	return ok || IsUTF8Charset(label)
+}
+
+// NewCharsetReader returns a reader that converts input from the named
+// charset to UTF-8. Its signature matches xml.Decoder.CharsetReader.
+func NewCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	if IsUTF8Charset(charset) {
+		return input, nil
+	}
+	table, ok := charsetTables[normaliseCharset(charset)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported charset: %s", charset)
+	}
+	return &charmapReader{src: input, table: table}, nil
+}
+
+// charmapReader decodes a single-byte charset to UTF-8 as it is read.
+type charmapReader struct {
+	src     io.Reader
+	table   *[256]rune
+	pending []byte
+	chunk   [2048]byte
+}
+
+func (r *charmapReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		n, err := r.src.Read(r.chunk[:])
+		for _, b := range r.chunk[:n] {
+			r.pending = utf8.AppendRune(r.pending, r.table[b])
+		}
+		if err != nil && len(r.pending) == 0 {
+			return 0, err
+		}
+		if err != nil {
+			break
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
@@ -2,35 +2,202 @@ package utils
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 )
 
-// ParseRSSDate parses common RSS date formats
+// zoneOffsets maps the time zone abbreviations found in feeds to their UTC
+// offsets in seconds. time.Parse only knows the abbreviations of the local
+// zone and silently treats all others as UTC, so they are resolved here.
+// Where an abbreviation is ambiguous the most common meaning in feeds wins.
+var zoneOffsets = map[string]int{
+	"UT":   0,
+	"UTC":  0,
+	"GMT":  0,
+	"Z":    0,
+	"WET":  0,
+	"WEST": 1 * 3600,
+	"BST":  1 * 3600,
+	"IST":  5*3600 + 1800,
+	"CET":  1 * 3600,
+	"CEST": 2 * 3600,
+	"MET":  1 * 3600,
+	"MEST": 2 * 3600,
+	"EET":  2 * 3600,
+	"EEST": 3 * 3600,
+	"MSK":  3 * 3600,
+	"HKT":  8 * 3600,
+	"SGT":  8 * 3600,
+	"AWST": 8 * 3600,
+	"JST":  9 * 3600,
+	"KST":  9 * 3600,
+	"ACST": 9*3600 + 1800,
+	"ACDT": 10*3600 + 1800,
+	"AEST": 10 * 3600,
+	"AEDT": 11 * 3600,
+	"NZST": 12 * 3600,
+	"NZDT": 13 * 3600,
+	"NST":  -(3*3600 + 1800),
+	"NDT":  -(2*3600 + 1800),
+	"AST":  -4 * 3600,
+	"ADT":  -3 * 3600,
+	"EST":  -5 * 3600,
+	"EDT":  -4 * 3600,
+	"CST":  -6 * 3600,
+	"CDT":  -5 * 3600,
+	"MST":  -7 * 3600,
+	"MDT":  -6 * 3600,
+	"PST":  -8 * 3600,
+	"PDT":  -7 * 3600,
+	"AKST": -9 * 3600,
+	"AKDT": -8 * 3600,
+	"HST":  -10 * 3600,
+}
+
+var weekdayNames = map[string]bool{
+	"mon": true, "tue": true, "tues": true, "wed": true, "thu": true, "thur": true, "thurs": true,
+	"fri": true, "sat": true, "sun": true, "monday": true, "tuesday": true, "wednesday": true,
+	"thursday": true, "friday": true, "saturday": true, "sunday": true,
+}
+
+// dateLayouts are tried in order once the weekday has been removed and any
+// zone has been rewritten as a numeric offset. Layouts without a zone are
+// read as UTC. Fractional seconds are accepted after any seconds field.
+var dateLayouts = []string{
+	// RFC 822 / RFC 1123 and their many variations
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 January 2006 15:04:05 -0700",
+	"2 January 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	"2 Jan 06 15:04:05",
+	"2 January 2006 15:04:05",
+	"2 Jan 2006",
+	"2 January 2006",
+	// Month first, as in ANSI C / Unix dates and some hand-written feeds
+	"Jan 2 15:04:05 -0700 2006",
+	"Jan 2 15:04:05 2006",
+	"Jan 2 2006 15:04:05 -0700",
+	"Jan 2 2006 15:04:05",
+	"Jan 2, 2006 15:04:05 -0700",
+	"Jan 2, 2006 15:04:05",
+	"January 2, 2006 15:04:05 -0700",
+	"January 2, 2006 15:04:05",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	// ISO 8601 / RFC 3339, as used by Atom, JSON Feed and dc:date
+	time.RFC3339,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05-07",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+}
+
+// ParseRSSDate parses a date in any of the formats commonly found in RSS,
+// Atom, RSS 1.0 (dc:date) and JSON feeds. Named zones such as EST or CEST,
+// offsets like GMT+2 and fractional seconds are understood; a date without
+// any zone is taken to be UTC.
 func ParseRSSDate(dateStr string) (time.Time, error) {
-    if dateStr == "" {
-        return time.Now(), nil
-    }
-
-    // Common RSS date formats
-    formats := []string{
-        time.RFC1123,     // "Mon, 02 Jan 2006 15:04:05 MST"
-        time.RFC1123Z,    // "Mon, 02 Jan 2006 15:04:05 -0700"
-        time.RFC822,      // "02 Jan 06 15:04 MST"
-        time.RFC822Z,     // "02 Jan 06 15:04 -0700"
-        time.RFC3339,     // "2006-01-02T15:04:05Z07:00"
-        "2006-01-02 15:04:05",
-        "2006-01-02T15:04:05",
-        "Mon, 2 Jan 2006 15:04:05 MST",
-        "Mon, 2 Jan 2006 15:04:05 -0700",
-    }
-
-    // Try each format
-    for _, format := range formats {
-        if t, err := time.Parse(format, strings.TrimSpace(dateStr)); err == nil {
-            return t, nil
-        }
-    }
-
-    return time.Time{}, fmt.Errorf("unable to parse date: %s", dateStr)
-}
\ No newline at end of file
+	normalised := normaliseDate(dateStr)
+	if normalised == "" {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, normalised); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse date: %s", dateStr)
+}
+
+// normaliseDate removes the (often wrong) weekday and any parenthesised
+// comment, and rewrites named or GMT-relative zones as numeric offsets.
+func normaliseDate(dateStr string) string {
+	if i := strings.IndexByte(dateStr, '('); i >= 0 {
+		dateStr = dateStr[:i]
+	}
+
+	fields := strings.Fields(dateStr)
+	if len(fields) > 0 {
+		first := strings.ToLower(strings.TrimRight(fields[0], ",."))
+		if weekdayNames[first] {
+			fields = fields[1:]
+		} else if comma := strings.IndexByte(fields[0], ','); comma > 0 && weekdayNames[strings.ToLower(fields[0][:comma])] {
+			// "Mon,02 Jan 2006" without a space after the comma
+			fields[0] = fields[0][comma+1:]
+		}
+	}
+
+	for i, field := range fields {
+		if offset, ok := zoneOffset(field); ok {
+			fields[i] = formatOffset(offset)
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// zoneOffset reads a zone written as an abbreviation, a numeric offset
+// (+0200, +02:00, +02) or an offset relative to GMT or UTC (GMT+2,
+// UTC-05:30), and returns it in seconds east of UTC.
+func zoneOffset(zone string) (int, bool) {
+	upper := strings.ToUpper(zone)
+	if offset, ok := zoneOffsets[upper]; ok {
+		return offset, true
+	}
+
+	for _, prefix := range []string{"GMT", "UTC", "UT"} {
+		if rest, found := strings.CutPrefix(upper, prefix); found && rest != "" {
+			upper = rest
+			break
+		}
+	}
+	if len(upper) < 2 || (upper[0] != '+' && upper[0] != '-') {
+		return 0, false
+	}
+
+	sign := 1
+	if upper[0] == '-' {
+		sign = -1
+	}
+	digits := strings.Replace(upper[1:], ":", "", 1)
+	var hours, minutes int
+	var err error
+	switch len(digits) {
+	case 1, 2:
+		hours, err = strconv.Atoi(digits)
+	case 3, 4:
+		hours, err = strconv.Atoi(digits[:len(digits)-2])
+		if err == nil {
+			minutes, err = strconv.Atoi(digits[len(digits)-2:])
+		}
+	default:
+		return 0, false
+	}
+	if err != nil || hours > 14 || minutes > 59 {
+		return 0, false
+	}
+	return sign * (hours*3600 + minutes*60), true
+}
+
+func formatOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, offset/3600, offset%3600/60)
+}
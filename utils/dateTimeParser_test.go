@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRSSDate(t *testing.T) {
+	utc := func(year int, month time.Month, day, hour, min, sec int) time.Time {
+		return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+	}
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		// RFC 822 and variations
+		{"Mon, 02 Jan 2006 15:04:05 GMT", utc(2006, 1, 2, 15, 4, 5)},
+		{"Mon, 02 Jan 2006 15:04:05 +0000", utc(2006, 1, 2, 15, 4, 5)},
+		{"Mon, 2 Jan 2006 17:04:05 +0200", utc(2006, 1, 2, 15, 4, 5)},
+		{"Mon,02 Jan 2006 15:04:05 GMT", utc(2006, 1, 2, 15, 4, 5)},
+		{"Tue, 02 Jan 2006 15:04:05 GMT", utc(2006, 1, 2, 15, 4, 5)},
+		{"Monday, 02 January 2006 15:04:05 GMT", utc(2006, 1, 2, 15, 4, 5)},
+		{"02 Jan 06 15:04 GMT", utc(2006, 1, 2, 15, 4, 0)},
+		{"Mon, 02 Jan 2006 10:04:05 EST", utc(2006, 1, 2, 15, 4, 5)},
+		{"Mon, 02 Jan 2006 17:04:05 CEST", utc(2006, 1, 2, 15, 4, 5)},
+		{"Mon, 02 Jan 2006 17:04:05 GMT+2", utc(2006, 1, 2, 15, 4, 5)},
+		{"Mon, 02 Jan 2006 09:34:05 UTC-05:30", utc(2006, 1, 2, 15, 4, 5)},
+		{"Mon, 02 Jan 2006 15:04:05 GMT (Coordinated Universal Time)", utc(2006, 1, 2, 15, 4, 5)},
+		{"02 Jan 2006", utc(2006, 1, 2, 0, 0, 0)},
+		// Month first
+		{"Mon Jan 2 15:04:05 2006", utc(2006, 1, 2, 15, 4, 5)},
+		{"January 2, 2006 15:04:05", utc(2006, 1, 2, 15, 4, 5)},
+		{"Jan 2, 2006", utc(2006, 1, 2, 0, 0, 0)},
+		// ISO 8601
+		{"2006-01-02T15:04:05Z", utc(2006, 1, 2, 15, 4, 5)},
+		{"2006-01-02T17:04:05+02:00", utc(2006, 1, 2, 15, 4, 5)},
+		{"2006-01-02T17:04:05+0200", utc(2006, 1, 2, 15, 4, 5)},
+		{"2006-01-02T15:04:05.123Z", time.Date(2006, 1, 2, 15, 4, 5, 123e6, time.UTC)},
+		{"2006-01-02T15:04:05", utc(2006, 1, 2, 15, 4, 5)},
+		{"2006-01-02 15:04:05", utc(2006, 1, 2, 15, 4, 5)},
+		{"2006-01-02", utc(2006, 1, 2, 0, 0, 0)},
+		{"2006/01/02", utc(2006, 1, 2, 0, 0, 0)},
+	}
+	for _, tt := range tests {
+		got, err := ParseRSSDate(tt.in)
+		if err != nil {
+			t.Errorf("ParseRSSDate(%q): %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseRSSDate(%q) = %v, want %v", tt.in, got.UTC(), tt.want)
+		}
+	}
+}
+
+func TestParseRSSDateRejects(t *testing.T) {
+	for _, in := range []string{"", "   ", "yesterday", "32 Jan 2006", "2006-13-01", "Mon, 02 Jan 2006 15:04:05 GMT+15"} {
+		if got, err := ParseRSSDate(in); err == nil {
+			t.Errorf("ParseRSSDate(%q) = %v, want an error", in, got)
+		}
+	}
+}
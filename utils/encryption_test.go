@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, EncryptionKeySize)
+	otherKey := bytes.Repeat([]byte{2}, EncryptionKeySize)
+	plaintext := []byte(`{"username":"alice","password":"secret"}`)
+	aad := []byte("owner-1")
+
+	sealed, err := Encrypt(key, plaintext, aad)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("secret")) {
+		t.Fatal("sealed value contains the plaintext")
+	}
+	again, _ := Encrypt(key, plaintext, aad)
+	if bytes.Equal(sealed, again) {
+		t.Error("two encryptions of the same value are identical")
+	}
+
+	opened, err := Decrypt(key, sealed, aad)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("Decrypt = %q, want %q", opened, plaintext)
+	}
+
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 1
+	tests := []struct {
+		name   string
+		key    []byte
+		sealed []byte
+		aad    []byte
+	}{
+		{"other additional data", key, sealed, []byte("owner-2")},
+		{"no additional data", key, sealed, nil},
+		{"other key", otherKey, sealed, aad},
+		{"tampered", key, tampered, aad},
+		{"too short", key, sealed[:4], aad},
+		{"short key", key[:16], sealed, aad},
+	}
+	for _, tt := range tests {
+		if _, err := Decrypt(tt.key, tt.sealed, tt.aad); err == nil {
+			t.Errorf("%s: Decrypt succeeded", tt.name)
+		}
+	}
+}
+
+func TestParseEncryptionKey(t *testing.T) {
+	valid := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, EncryptionKeySize))
+	if key, err := ParseEncryptionKey(" " + valid + "\n"); err != nil || len(key) != EncryptionKeySize {
+		t.Errorf("ParseEncryptionKey(valid) = %d bytes, %v", len(key), err)
+	}
+	short := base64.StdEncoding.EncodeToString(make([]byte, 16))
+	for _, encoded := range []string{"", "not base64!", short} {
+		if _, err := ParseEncryptionKey(encoded); err == nil {
+			t.Errorf("ParseEncryptionKey(%q) succeeded", encoded)
+		}
+	}
+}
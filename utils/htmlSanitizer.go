@@ -0,0 +1,264 @@
+package utils
+
+import (
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// allowedTags maps each allowed element to the attributes it may carry.
+var allowedTags = map[string]map[string]bool{
+	"a":          {"href": true, "title": true},
+	"abbr":       {"title": true},
+	"b":          {},
+	"blockquote": {"cite": true},
+	"br":         {},
+	"caption":    {},
+	"cite":       {},
+	"code":       {},
+	"dd":         {},
+	"del":        {},
+	"details":    {},
+	"div":        {},
+	"dl":         {},
+	"dt":         {},
+	"em":         {},
+	"figcaption": {},
+	"figure":     {},
+	"h1":         {},
+	"h2":         {},
+	"h3":         {},
+	"h4":         {},
+	"h5":         {},
+	"h6":         {},
+	"hr":         {},
+	"i":          {},
+	"img":        {"src": true, "alt": true, "title": true, "width": true, "height": true},
+	"ins":        {},
+	"kbd":        {},
+	"li":         {},
+	"mark":       {},
+	"ol":         {"start": true, "type": true},
+	"p":          {},
+	"pre":        {},
+	"q":          {"cite": true},
+	"s":          {},
+	"small":      {},
+	"span":       {},
+	"strong":     {},
+	"sub":        {},
+	"summary":    {},
+	"sup":        {},
+	"table":      {},
+	"tbody":      {},
+	"td":         {"colspan": true, "rowspan": true, "align": true},
+	"tfoot":      {},
+	"th":         {"colspan": true, "rowspan": true, "align": true, "scope": true},
+	"thead":      {},
+	"time":       {"datetime": true},
+	"tr":         {},
+	"u":          {},
+	"ul":         {},
+	"audio":      {"src": true, "controls": true},
+	"video":      {"src": true, "controls": true, "poster": true, "width": true, "height": true},
+	"source":     {"src": true, "type": true},
+}
+
+// globalAttributes may appear on any allowed element.
+var globalAttributes = map[string]bool{
+	"lang": true,
+	"dir":  true,
+}
+
+// urlAttributes hold URLs and are checked against allowedSchemes.
+var urlAttributes = map[string]bool{
+	"href":   true,
+	"src":    true,
+	"cite":   true,
+	"poster": true,
+}
+
+var allowedSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+// droppedTags are removed together with everything inside them: markup that
+// is never rendered as HTML. The content of the raw text elements, such as
+// scripts and styles, is removed as well. Any other element that is not
+// allowed loses its tag but keeps its content, so that a whole page or an
+// unclosed <form> still yields the article inside it.
+var droppedTags = map[string]bool{
+	"template": true,
+	"svg":      true,
+	"math":     true,
+}
+
+var voidTags = map[string]bool{
+	"br":     true,
+	"hr":     true,
+	"img":    true,
+	"source": true,
+}
+
+// SanitizeHTML filters untrusted HTML from a feed through a tag and attribute
+// allowlist. Scripts, event handlers and non-http(s) URLs are removed, 1x1
+// tracking images are dropped and links get rel="noopener noreferrer".
+// Relative URLs are resolved against baseURL when it is a valid absolute URL.
+func SanitizeHTML(raw string, baseURL string) string {
+	if raw == "" {
+		return ""
+	}
+
+	base, err := url.Parse(baseURL)
+	if err != nil || !base.IsAbs() {
+		base = nil
+	}
+
+	var out strings.Builder
+	var open []string
+	dropping := ""
+	dropDepth := 0
+
+	tokens := TokenizeHTML(raw)
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if dropping != "" {
+			switch {
+			case token.Type == HTMLStartTagToken && token.Name == dropping:
+				dropDepth++
+			case token.Type == HTMLEndTagToken && token.Name == dropping:
+				dropDepth--
+				if dropDepth == 0 {
+					dropping = ""
+				}
+			}
+			continue
+		}
+
+		switch token.Type {
+		case HTMLTextToken:
+			out.WriteString(html.EscapeString(html.UnescapeString(token.Data)))
+		case HTMLStartTagToken, HTMLSelfClosingTagToken:
+			if _, ok := rawTextElements[token.Name]; ok {
+				if token.Type == HTMLStartTagToken {
+					i = skipRawText(tokens, i)
+				}
+				continue
+			}
+			if droppedTags[token.Name] {
+				if token.Type == HTMLStartTagToken {
+					dropping = token.Name
+					dropDepth = 1
+				}
+				continue
+			}
+			allowedAttrs, ok := allowedTags[token.Name]
+			if !ok || isTrackingPixel(token) {
+				continue
+			}
+			out.WriteString("<" + token.Name)
+			for _, attr := range token.Attrs {
+				if !allowedAttrs[attr.Name] && !globalAttributes[attr.Name] {
+					continue
+				}
+				value := attr.Value
+				if urlAttributes[attr.Name] {
+					var ok bool
+					if value, ok = sanitizeURL(value, base); !ok {
+						continue
+					}
+				}
+				out.WriteString(" " + attr.Name + `="` + html.EscapeString(value) + `"`)
+			}
+			if token.Name == "a" {
+				out.WriteString(` rel="noopener noreferrer"`)
+			}
+			out.WriteString(">")
+			if !voidTags[token.Name] && token.Type == HTMLStartTagToken {
+				open = append(open, token.Name)
+			}
+		case HTMLEndTagToken:
+			// Only close elements we actually opened, closing any left open inside them
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == token.Name {
+					for j := len(open) - 1; j >= i; j-- {
+						out.WriteString("</" + open[j] + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		out.WriteString("</" + open[i] + ">")
+	}
+	return out.String()
+}
+
+// skipRawText returns the index of the last token of the raw text element
+// that starts at tokens[i]: its end tag, or its text when it runs to the end
+// of the document. An element the tokenizer left unclosed has no raw text,
+// and only its start tag is skipped.
+func skipRawText(tokens []HTMLToken, i int) int {
+	j := i + 1
+	if j < len(tokens) && tokens[j].Type == HTMLTextToken {
+		j++
+	}
+	switch {
+	case j == len(tokens):
+		return j - 1
+	case tokens[j].Type == HTMLEndTagToken && tokens[j].Name == tokens[i].Name:
+		return j
+	default:
+		return i
+	}
+}
+
+// sanitizeURL returns the URL to keep for a URL attribute, resolved against
+// base when it is not nil, or false if the attribute should be dropped.
+func sanitizeURL(value string, base *url.URL) (string, bool) {
+	// Browsers ignore whitespace and control characters inside schemes, so
+	// "java\tscript:" must be caught as well
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, value)
+	if cleaned == "" {
+		return "", false
+	}
+
+	parsed, err := url.Parse(cleaned)
+	if err != nil {
+		return "", false
+	}
+	if parsed.Scheme != "" && !allowedSchemes[strings.ToLower(parsed.Scheme)] {
+		return "", false
+	}
+	if base != nil && !parsed.IsAbs() {
+		parsed = base.ResolveReference(parsed)
+	}
+	return parsed.String(), true
+}
+
+// isTrackingPixel reports whether an img token is a 1x1 (or smaller) image.
+func isTrackingPixel(token HTMLToken) bool {
+	if token.Name != "img" {
+		return false
+	}
+	width, widthOK := pixelSize(token.Attr("width"))
+	height, heightOK := pixelSize(token.Attr("height"))
+	return widthOK && heightOK && width <= 1 && height <= 1
+}
+
+func pixelSize(value string) (int, bool) {
+	value = strings.TrimSuffix(strings.TrimSpace(strings.ToLower(value)), "px")
+	size, err := strconv.Atoi(value)
+	return size, err == nil
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeHTMLRemovesActiveContent(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"script", `<p>a</p><script>alert(1)</script>`},
+		{"script in upper case", `<SCRIPT>alert(1)</SCRIPT>`},
+		{"unclosed script", `<p>a</p><script>alert(1)`},
+		{"markup inside script", `<script>document.write("<p onclick=alert(1)>")</script>`},
+		{"style", `<style>body{background:url(javascript:alert(1))}</style>`},
+		{"event handler", `<img src="x.png" onerror="alert(1)">`},
+		{"javascript link", `<a href="javascript:alert(1)">x</a>`},
+		{"obfuscated scheme", `<a href="java&#x09;script:alert(1)">x</a>`},
+		{"data uri", `<img src="data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">`},
+		{"vbscript", `<a href=" vbscript:msgbox(1)">x</a>`},
+		{"iframe", `<iframe src="https://evil.example/" onload="alert(1)"></iframe>`},
+		{"svg", `<svg><script>alert(1)</script><a href="javascript:alert(1)">x</a></svg>`},
+		{"template", `<template><img src=x onerror=alert(1)></template>`},
+		{"noscript", `<noscript><p title="</noscript><img src=x onerror=alert(1)>"></noscript>`},
+		{"form action", `<form action="javascript:alert(1)"><button formaction="javascript:alert(1)">x</button></form>`},
+		{"style attribute", `<p style="background:url(javascript:alert(1))">a</p>`},
+		{"unclosed title", `<title><script>alert(1)</script>`},
+		{"meta refresh", `<meta http-equiv="refresh" content="0;url=javascript:alert(1)">`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.ToLower(SanitizeHTML(tt.in, "https://example.com/"))
+			for _, bad := range []string{"<script", "alert(1)", "javascript:", "vbscript:", "data:", "onerror", "onload", "<iframe", "<svg", "<form", "<meta", "style="} {
+				if strings.Contains(got, bad) {
+					t.Errorf("SanitizeHTML(%q) = %q, contains %q", tt.in, got, bad)
+				}
+			}
+		})
+	}
+}
+
+func TestSanitizeHTMLKeepsContent(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"whole document", `<html><head><meta charset=utf-8><body><p>Article body</p></body></html>`, `<p>Article body</p>`},
+		{"head with title", `<html><head><title>Page</title><style>p{}</style></head><body><p>Article body</p></body></html>`, `<p>Article body</p>`},
+		{"unclosed form", `<form><p>Article body</p>`, `<p>Article body</p>`},
+		{"closed form", `<form><p>Inside</p></form><p>After</p>`, `<p>Inside</p><p>After</p>`},
+		{"stray title", `<p>Before</p><title><p>After</p>`, `<p>Before</p><p>After</p>`},
+		{"unclosed iframe", `<p>Before</p><iframe src="x"><p>After</p>`, `<p>Before</p><p>After</p>`},
+		{"object fallback", `<object data="movie.swf"><p>Fallback</p></object>`, `<p>Fallback</p>`},
+		{"closed script", `<p>Before</p><script>x()</script><p>After</p>`, `<p>Before</p><p>After</p>`},
+		{"unknown element", `<custom-card><p>Text</p></custom-card>`, `<p>Text</p>`},
+		{"relative link", `<a href="/post">Post</a>`, `<a href="https://example.com/post" rel="noopener noreferrer">Post</a>`},
+		{"image", `<img src="a.png" alt="A">`, `<img src="https://example.com/a.png" alt="A">`},
+		{"tracking pixel", `<p>Text<img src="t.gif" width="1" height="1"></p>`, `<p>Text</p>`},
+		{"unclosed elements", `<p><b>Bold`, `<p><b>Bold</b></p>`},
+		{"escaped text", `<p>1 &lt; 2 &amp; 3 > 2</p>`, `<p>1 &lt; 2 &amp; 3 &gt; 2</p>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeHTML(tt.in, "https://example.com/"); got != tt.want {
+				t.Errorf("SanitizeHTML(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
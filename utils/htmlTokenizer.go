@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"html"
+	"strings"
+)
+
+type HTMLTokenType int
+
+const (
+	HTMLTextToken HTMLTokenType = iota
+	HTMLStartTagToken
+	HTMLEndTagToken
+	HTMLSelfClosingTagToken
+	HTMLCommentToken
+	HTMLDoctypeToken
+)
+
+type HTMLAttribute struct {
+	Name  string
+	Value string
+}
+
+// HTMLToken is a single lexical token of an HTML document. For tags, Name is
+// lower-cased and attribute values are unescaped. For text tokens, Data holds
+// the raw (still escaped) text.
+type HTMLToken struct {
+	Type  HTMLTokenType
+	Name  string
+	Attrs []HTMLAttribute
+	Data  string
+}
+
+// Attr returns the value of the named attribute, or "" if it is absent.
+func (t HTMLToken) Attr(name string) string {
+	for _, attr := range t.Attrs {
+		if attr.Name == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// rawTextElements hold text that must not be tokenized as markup. The value
+// says whether one that is never closed holds the rest of the document, as
+// browsers have it. That is only worth it for code; a stray <title> or
+// <iframe> is not allowed to swallow an article, so after one of those the
+// rest is tokenized as usual.
+var rawTextElements = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"iframe":   false,
+	"noembed":  false,
+	"noframes": false,
+	"noscript": false,
+	"textarea": false,
+	"title":    false,
+	"xmp":      false,
+}
+
+// TokenizeHTML splits an HTML document into tokens. It is a lenient lexer,
+// not a full HTML5 parser: it does not build a tree or fix up nesting, which
+// is all feed discovery and sanitising need.
+func TokenizeHTML(doc string) []HTMLToken {
+	var tokens []HTMLToken
+	pos := 0
+	for pos < len(doc) {
+		lt := strings.IndexByte(doc[pos:], '<')
+		if lt < 0 {
+			tokens = append(tokens, HTMLToken{Type: HTMLTextToken, Data: doc[pos:]})
+			break
+		}
+		if lt > 0 {
+			tokens = append(tokens, HTMLToken{Type: HTMLTextToken, Data: doc[pos : pos+lt]})
+			pos += lt
+		}
+
+		rest := doc[pos:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				tokens = append(tokens, HTMLToken{Type: HTMLCommentToken, Data: rest[4:]})
+				return tokens
+			}
+			tokens = append(tokens, HTMLToken{Type: HTMLCommentToken, Data: rest[4 : 4+end]})
+			pos += 4 + end + 3
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return tokens
+			}
+			tokens = append(tokens, HTMLToken{Type: HTMLDoctypeToken, Data: rest[2:end]})
+			pos += end + 1
+		case len(rest) > 1 && (isASCIILetter(rest[1]) || (rest[1] == '/' && len(rest) > 2 && isASCIILetter(rest[2]))):
+			token, n := readHTMLTag(rest)
+			tokens = append(tokens, token)
+			pos += n
+			toEnd, raw := rawTextElements[token.Name]
+			if token.Type == HTMLStartTagToken && raw {
+				closing := "</" + token.Name
+				end := strings.Index(strings.ToLower(doc[pos:]), closing)
+				if end < 0 && !toEnd {
+					continue
+				}
+				if end < 0 {
+					end = len(doc) - pos
+				}
+				if end > 0 {
+					tokens = append(tokens, HTMLToken{Type: HTMLTextToken, Data: doc[pos : pos+end]})
+				}
+				pos += end
+			}
+		default:
+			// A stray '<' that does not open a tag is plain text.
+			tokens = append(tokens, HTMLToken{Type: HTMLTextToken, Data: "<"})
+			pos++
+		}
+	}
+	return tokens
+}
+
+// readHTMLTag reads a start or end tag at the beginning of s and returns the
+// token together with the number of bytes consumed.
+func readHTMLTag(s string) (HTMLToken, int) {
+	token := HTMLToken{Type: HTMLStartTagToken}
+	i := 1
+	if s[i] == '/' {
+		token.Type = HTMLEndTagToken
+		i++
+	}
+	start := i
+	for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' && s[i] != '/' {
+		i++
+	}
+	token.Name = strings.ToLower(s[start:i])
+
+	for i < len(s) {
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+		if s[i] == '>' {
+			return token, i + 1
+		}
+		if s[i] == '/' {
+			if i+1 < len(s) && s[i+1] == '>' {
+				if token.Type == HTMLStartTagToken {
+					token.Type = HTMLSelfClosingTagToken
+				}
+				return token, i + 2
+			}
+			i++
+			continue
+		}
+
+		nameStart := i
+		for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		attr := HTMLAttribute{Name: strings.ToLower(s[nameStart:i])}
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isHTMLSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				quote := s[i]
+				i++
+				valueStart := i
+				for i < len(s) && s[i] != quote {
+					i++
+				}
+				attr.Value = html.UnescapeString(s[valueStart:i])
+				if i < len(s) {
+					i++
+				}
+			} else {
+				valueStart := i
+				for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				attr.Value = html.UnescapeString(s[valueStart:i])
+			}
+		}
+		if token.Type != HTMLEndTagToken && attr.Name != "" {
+			token.Attrs = append(token.Attrs, attr)
+		}
+	}
+	return token, len(s)
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -7,18 +7,17 @@ import (
 )
 
 func RespondWithJSON(w http.ResponseWriter, status int, data interface{}) {
-	// Encode the data as JSON and write it to the response
-	if jsonData, err := json.Marshal(data); err != nil {
+	// Encode the data as JSON
+	jsonData, err := json.Marshal(data)
+	if err != nil {
 		log.Printf("Error encoding JSON response: %v", data)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
-	} else {
-		w.Write(jsonData)
 	}
-	// Set the content type to application/json
+	// Headers and status must be set before the body is written
 	w.Header().Add("Content-Type", "application/json")
-	// Set the HTTP status code
 	w.WriteHeader(status)
+	w.Write(jsonData)
 }
 
 func RespondWithError(w http.ResponseWriter, status int, message string) {